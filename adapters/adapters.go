@@ -3,57 +3,298 @@ package adapters
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/FrenchMajesty/consistent-classifier/adapters/pinecone"
 	"github.com/FrenchMajesty/consistent-classifier/adapters/voyage"
+	"github.com/FrenchMajesty/consistent-classifier/internal/ratelimit"
+	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/internal/tracing"
 	"github.com/FrenchMajesty/consistent-classifier/types"
+	"github.com/austinfhunter/voyageai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
+const (
+	// DefaultVoyageTimeout bounds each Voyage embedding request attempt
+	DefaultVoyageTimeout = 10 * time.Second
+
+	// DefaultPineconeTimeout bounds each Pinecone request attempt
+	DefaultPineconeTimeout = 5 * time.Second
+
+	// PineconeBatchSize is the most vectors sent in one Pinecone upsert, fetch or delete request.
+	// Larger calls are split into several requests.
+	PineconeBatchSize = 100
+)
+
 // VoyageEmbeddingAdapter adapts the Voyage client to the EmbeddingClient interface
 type VoyageEmbeddingAdapter struct {
 	client interface {
 		GenerateEmbedding(ctx context.Context, text string, embeddingType voyage.VoyageEmbeddingType) ([]float32, error)
+		GenerateEmbeddings(ctx context.Context, texts []string, embeddingType voyage.VoyageEmbeddingType) ([]voyageai.EmbeddingObject, error)
 	}
+	inputType   types.EmbeddingInputType
+	dimensions  int // Expected embedding dimension. If 0, not checked.
+	retryConfig retry.Config
+	timeout     time.Duration
+	tracer      trace.Tracer
+	logger      *slog.Logger
+	metrics     types.MetricsRecorder
+	breaker     *retry.Breaker
+	hedger      *retry.Hedger
+	limiter     *ratelimit.Limiter
+}
+
+// VoyageConfig configures a VoyageEmbeddingAdapter
+type VoyageConfig struct {
+	// APIKey is the Voyage API key. If nil, reads VOYAGEAI_API_KEY.
+	APIKey *string
+
+	// Model is the Voyage embedding model. If empty, uses voyage.VOYAGEAI_EMBEDDING_MODEL.
+	Model string
+
+	// Dimensions is the embedding dimension to request. Returned embeddings of any other dimension
+	// are rejected. If 0, uses voyage.EMBEDDING_DIMENSIONS.
+	Dimensions int
+
+	// InputType is sent by GenerateEmbedding. The classifier overrides it with query for cache
+	// lookups and document for cache writes. If empty, the input type is left unset.
+	InputType types.EmbeddingInputType
+
+	// Retry configures retries of failed requests. If nil, uses DefaultRetryConfig().
+	Retry *RetryConfig
+
+	// Timeout bounds each request attempt. If 0, uses DefaultVoyageTimeout.
+	Timeout time.Duration
 }
 
 // NewVoyageEmbeddingAdapter creates a new adapter for Voyage AI
 func NewVoyageEmbeddingAdapter(apiKey *string) (*VoyageEmbeddingAdapter, error) {
-	key, err := loadEnvVar(apiKey, "VOYAGEAI_API_KEY")
+	return NewVoyageEmbeddingAdapterWithConfig(VoyageConfig{APIKey: apiKey})
+}
+
+// NewVoyageEmbeddingAdapterWithConfig creates a new adapter for Voyage AI with custom retries and timeout
+func NewVoyageEmbeddingAdapterWithConfig(cfg VoyageConfig) (*VoyageEmbeddingAdapter, error) {
+	key, err := loadEnvVar(cfg.APIKey, "VOYAGEAI_API_KEY")
 	if err != nil {
 		return nil, err
 	}
 
-	return &VoyageEmbeddingAdapter{
-		client: voyage.NewEmbeddingService(*key),
-	}, nil
+	service := voyage.NewEmbeddingService(*key)
+	if cfg.Model != "" {
+		service.SetModel(cfg.Model)
+	}
+	if cfg.Dimensions != 0 {
+		service.SetDimensions(cfg.Dimensions)
+	}
+
+	adapter := &VoyageEmbeddingAdapter{
+		client:      service,
+		inputType:   cfg.InputType,
+		dimensions:  service.GetEmbeddingDimensions(),
+		retryConfig: retry.DefaultConfig(),
+		timeout:     DefaultVoyageTimeout,
+		breaker:     retry.NewBreaker("Voyage", retry.DefaultBreakerConfig()),
+	}
+	if cfg.Retry != nil {
+		adapter.retryConfig = *cfg.Retry
+	}
+	if cfg.Timeout != 0 {
+		adapter.timeout = cfg.Timeout
+	}
+
+	return adapter, nil
+}
+
+// Dimensions returns the dimension of the embeddings the adapter generates
+func (a *VoyageEmbeddingAdapter) Dimensions() int {
+	return a.dimensions
+}
+
+// SetTracerProvider creates spans for embedding requests using the given provider
+func (a *VoyageEmbeddingAdapter) SetTracerProvider(tp trace.TracerProvider) {
+	a.tracer = tracing.Tracer(tp)
+}
+
+// SetLogger logs embedding requests to the given logger
+func (a *VoyageEmbeddingAdapter) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
+// SetMetricsRecorder reports retry and hedge counts and rate limit waits to the given recorder
+func (a *VoyageEmbeddingAdapter) SetMetricsRecorder(recorder types.MetricsRecorder) {
+	a.metrics = recorder
 }
 
-// GenerateEmbedding implements EmbeddingClient interface
+// SetCircuitBreaker replaces the circuit breaker guarding embedding requests. nil disables it.
+func (a *VoyageEmbeddingAdapter) SetCircuitBreaker(breaker *CircuitBreaker) {
+	a.breaker = breaker
+}
+
+// SetHedger hedges slow embedding requests. nil, the default, disables hedging.
+func (a *VoyageEmbeddingAdapter) SetHedger(hedger *Hedger) {
+	a.hedger = hedger
+}
+
+// SetRateLimiter throttles embedding requests. Share the limiter between adapters using the same API key.
+func (a *VoyageEmbeddingAdapter) SetRateLimiter(limiter *RateLimiter) {
+	a.limiter = limiter
+}
+
+// GenerateEmbedding implements EmbeddingClient interface, using the adapter's configured input type
 func (a *VoyageEmbeddingAdapter) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	return a.client.GenerateEmbedding(ctx, text, voyage.VoyageEmbeddingTypeDefault)
+	return a.GenerateEmbeddingForInput(ctx, text, a.inputType)
+}
+
+// GenerateEmbeddingForInput embeds text for the given use, e.g. types.EmbeddingInputQuery for searches
+func (a *VoyageEmbeddingAdapter) GenerateEmbeddingForInput(ctx context.Context, text string, inputType types.EmbeddingInputType) (_ []float32, finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "voyage.embed", attribute.String("embedding.input_type", string(inputType)))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "embed", start, finalErr)
+	}()
+
+	opts := a.callOptions()
+	opts.tokens = ratelimit.EstimateTokens(text)
+	embedding, err := callProvider(ctx, opts, types.ProviderVoyage, "embed", func(ctx context.Context) ([]float32, error) {
+		return a.client.GenerateEmbedding(ctx, text, voyage.VoyageEmbeddingType(inputType))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDimensions(embedding, a.dimensions); err != nil {
+		return nil, err
+	}
+
+	return embedding, nil
 }
 
-// PineconeVectorAdapter adapts the Pinecone client to the VectorClient interface
+// GenerateEmbeddings embeds several texts with one request, returning embeddings in the order of texts.
+// An empty inputType uses the adapter's configured input type.
+func (a *VoyageEmbeddingAdapter) GenerateEmbeddings(ctx context.Context, texts []string, inputType types.EmbeddingInputType) (_ [][]float32, finalErr error) {
+	if inputType == types.EmbeddingInputDefault {
+		inputType = a.inputType
+	}
+	ctx, span := tracing.Start(ctx, a.tracer, "voyage.embed_batch",
+		attribute.Int("embedding.batch_size", len(texts)),
+		attribute.String("embedding.input_type", string(inputType)),
+	)
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "embed_batch", start, finalErr, slog.Int("batch_size", len(texts)))
+	}()
+
+	opts := a.callOptions()
+	for _, text := range texts {
+		opts.tokens += ratelimit.EstimateTokens(text)
+	}
+	objects, err := callProvider(ctx, opts, types.ProviderVoyage, "embed_batch", func(ctx context.Context) ([]voyageai.EmbeddingObject, error) {
+		return a.client.GenerateEmbeddings(ctx, texts, voyage.VoyageEmbeddingType(inputType))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, object := range objects {
+		if object.Index < 0 || object.Index >= len(texts) {
+			return nil, fmt.Errorf("voyage returned embedding for index %d of %d texts", object.Index, len(texts))
+		}
+		embeddings[object.Index] = object.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("voyage returned no embedding for text %d", i)
+		}
+		if err := checkDimensions(embedding, a.dimensions); err != nil {
+			return nil, err
+		}
+	}
+
+	return embeddings, nil
+}
+
+// callOptions returns the retry, timeout and circuit breaker settings for Voyage requests
+func (a *VoyageEmbeddingAdapter) callOptions() callOptions {
+	return callOptions{
+		retry:   a.retryConfig,
+		timeout: a.timeout,
+		breaker: a.breaker,
+		hedger:  a.hedger,
+		limiter: a.limiter,
+		metrics: a.metrics,
+		tracer:  a.tracer,
+		logger:  a.logger,
+	}
+}
+
+// PineconeVectorAdapter adapts the Pinecone client to the VectorClient and ExtendedVectorClient interfaces
 type PineconeVectorAdapter struct {
 	index interface {
 		Search(ctx context.Context, queryVector []float32, topK int, filter map[string]any, includeMetadata bool) ([]pinecone.QueryMatch, error)
 		Upsert(ctx context.Context, vectors []pinecone.Vector) error
+		Fetch(ctx context.Context, ids []string) (map[string]*pinecone.Vector, error)
+		UpdateMetadata(ctx context.Context, vectorID string, metadata *pinecone.Metadata) error
+		Delete(ctx context.Context, ids []string) error
+		Dimension(ctx context.Context) (int, error)
 	}
+	dimension   int // Index dimension. If 0, vectors aren't checked.
+	retryConfig retry.Config
+	timeout     time.Duration
+	tracer      trace.Tracer
+	logger      *slog.Logger
+	metrics     types.MetricsRecorder
+	breaker     *retry.Breaker
+	hedger      *retry.Hedger
+	limiter     *ratelimit.Limiter
+}
+
+// PineconeConfig configures a PineconeVectorAdapter
+type PineconeConfig struct {
+	// APIKey is the Pinecone API key. If nil, reads PINECONE_API_KEY.
+	APIKey *string
+
+	// Host is the index host. If nil, reads PINECONE_HOST.
+	Host *string
+
+	// Namespace isolates the adapter's vectors within the index
+	Namespace string
+
+	// Dimension is the index dimension. Searches and upserts with vectors of any other dimension
+	// fail with ErrDimensionMismatch before reaching Pinecone. If 0, vectors aren't checked.
+	Dimension int
+
+	// Retry configures retries of failed requests. If nil, uses DefaultRetryConfig().
+	Retry *RetryConfig
+
+	// Timeout bounds each request attempt. If 0, uses DefaultPineconeTimeout.
+	Timeout time.Duration
 }
 
 // NewPineconeVectorAdapter creates a new adapter for Pinecone
 func NewPineconeVectorAdapter(apiKey *string, host *string, namespace string) (*PineconeVectorAdapter, error) {
-	key, err := loadEnvVar(apiKey, "PINECONE_API_KEY")
+	return NewPineconeVectorAdapterWithConfig(PineconeConfig{APIKey: apiKey, Host: host, Namespace: namespace})
+}
+
+// NewPineconeVectorAdapterWithConfig creates a new adapter for Pinecone with custom retries and timeout
+func NewPineconeVectorAdapterWithConfig(cfg PineconeConfig) (*PineconeVectorAdapter, error) {
+	key, err := loadEnvVar(cfg.APIKey, "PINECONE_API_KEY")
 	if err != nil {
 		return nil, err
 	}
 
-	h, err := loadEnvVar(host, "PINECONE_HOST")
+	h, err := loadEnvVar(cfg.Host, "PINECONE_HOST")
 	if err != nil {
 		return nil, err
 	}
+	namespace := cfg.Namespace
 
 	client, err := pinecone.NewPineconeService(*key)
 	if err != nil {
@@ -65,14 +306,96 @@ func NewPineconeVectorAdapter(apiKey *string, host *string, namespace string) (*
 		return nil, fmt.Errorf("failed to connect to pinecone index: %w", err)
 	}
 
-	return &PineconeVectorAdapter{
-		index: index,
-	}, nil
+	adapter := &PineconeVectorAdapter{
+		index:       index,
+		dimension:   cfg.Dimension,
+		retryConfig: retry.DefaultConfig(),
+		timeout:     DefaultPineconeTimeout,
+		breaker:     retry.NewBreaker("Pinecone "+namespace, retry.DefaultBreakerConfig()),
+	}
+	if cfg.Retry != nil {
+		adapter.retryConfig = *cfg.Retry
+	}
+	if cfg.Timeout != 0 {
+		adapter.timeout = cfg.Timeout
+	}
+
+	return adapter, nil
+}
+
+// SetTracerProvider creates spans for vector requests using the given provider
+func (a *PineconeVectorAdapter) SetTracerProvider(tp trace.TracerProvider) {
+	a.tracer = tracing.Tracer(tp)
+}
+
+// SetLogger logs vector requests to the given logger
+func (a *PineconeVectorAdapter) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
+// SetMetricsRecorder reports retry and hedge counts and rate limit waits to the given recorder
+func (a *PineconeVectorAdapter) SetMetricsRecorder(recorder types.MetricsRecorder) {
+	a.metrics = recorder
+}
+
+// SetCircuitBreaker replaces the circuit breaker guarding vector requests. nil disables it.
+func (a *PineconeVectorAdapter) SetCircuitBreaker(breaker *CircuitBreaker) {
+	a.breaker = breaker
+}
+
+// SetHedger hedges slow searches. Upserts are never hedged. nil, the default, disables hedging.
+func (a *PineconeVectorAdapter) SetHedger(hedger *Hedger) {
+	a.hedger = hedger
+}
+
+// SetRateLimiter throttles searches and upserts. Share the limiter between adapters using the same index.
+func (a *PineconeVectorAdapter) SetRateLimiter(limiter *RateLimiter) {
+	a.limiter = limiter
+}
+
+// MatchDimension checks vectors against the given dimension from now on, and verifies that the
+// index stores vectors of that dimension. Returns an error matching types.ErrDimensionMismatch if
+// it doesn't, or the error describing the index failed with. The index is described once, without
+// retries, so a check at startup fails fast while Pinecone is unavailable.
+func (a *PineconeVectorAdapter) MatchDimension(ctx context.Context, dimension int) error {
+	a.dimension = dimension
+
+	opts := a.callOptions(false)
+	opts.retry.MaxRetries = 0
+	indexDimension, err := callProvider(ctx, opts, types.ProviderPinecone, "describe", func(ctx context.Context) (int, error) {
+		return a.index.Dimension(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	if indexDimension != dimension {
+		return fmt.Errorf("%w: index has %d, expected %d", types.ErrDimensionMismatch, indexDimension, dimension)
+	}
+	return nil
 }
 
 // Search implements VectorClient interface
 func (a *PineconeVectorAdapter) Search(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
-	matches, err := a.index.Search(ctx, vector, topK, nil, true)
+	return a.SearchWithFilter(ctx, vector, topK, nil)
+}
+
+// SearchWithFilter implements ExtendedVectorClient interface. filter uses Pinecone's metadata
+// filter syntax, e.g. {"tenant": {"$eq": "acme"}}. A nil filter matches every vector.
+func (a *PineconeVectorAdapter) SearchWithFilter(ctx context.Context, vector []float32, topK int, filter map[string]any) (_ []types.VectorMatch, finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "pinecone.search", attribute.Int("vector.top_k", topK), attribute.Bool("vector.filtered", filter != nil))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "search", start, finalErr, slog.Int("top_k", topK))
+	}()
+
+	if err := checkDimensions(vector, a.dimension); err != nil {
+		return nil, err
+	}
+
+	matches, err := callProvider(ctx, a.callOptions(true), types.ProviderPinecone, "search", func(ctx context.Context) ([]pinecone.QueryMatch, error) {
+		return a.index.Search(ctx, vector, topK, filter, true)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -96,24 +419,176 @@ func (a *PineconeVectorAdapter) Search(ctx context.Context, vector []float32, to
 }
 
 // Upsert implements VectorClient interface
-func (a *PineconeVectorAdapter) Upsert(ctx context.Context, id string, vector []float32, metadata map[string]any) error {
-	// Convert metadata to structpb format
+func (a *PineconeVectorAdapter) Upsert(ctx context.Context, id string, vector []float32, metadata map[string]any) (finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "pinecone.upsert")
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "upsert", start, finalErr, slog.String("id", id))
+	}()
+
+	return a.upsert(ctx, []types.VectorRecord{{ID: id, Vector: vector, Metadata: metadata}})
+}
+
+// BatchUpsert implements ExtendedVectorClient interface, sending at most PineconeBatchSize vectors per request
+func (a *PineconeVectorAdapter) BatchUpsert(ctx context.Context, records []types.VectorRecord) (finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "pinecone.batch_upsert", attribute.Int("vector.count", len(records)))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "batch_upsert", start, finalErr, slog.Int("count", len(records)))
+	}()
+
+	return a.upsert(ctx, records)
+}
+
+// upsert checks and converts records, then stores them in batches
+func (a *PineconeVectorAdapter) upsert(ctx context.Context, records []types.VectorRecord) error {
+	vectors := make([]pinecone.Vector, len(records))
+	for i, record := range records {
+		if err := checkDimensions(record.Vector, a.dimension); err != nil {
+			return fmt.Errorf("vector %s: %w", record.ID, err)
+		}
+
+		// Convert metadata to structpb format
+		metadataStruct, err := structpb.NewStruct(record.Metadata)
+		if err != nil {
+			return err
+		}
+
+		vectors[i] = pinecone.Vector{
+			Id:     record.ID,
+			Values: record.Vector,
+			Metadata: &pinecone.Metadata{
+				Fields: metadataStruct.Fields,
+			},
+		}
+	}
+
+	for batch := range slices.Chunk(vectors, PineconeBatchSize) {
+		_, err := callProvider(ctx, a.callOptions(false), types.ProviderPinecone, "upsert", func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, a.index.Upsert(ctx, batch)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fetch implements ExtendedVectorClient interface. IDs that aren't stored are absent from the result.
+func (a *PineconeVectorAdapter) Fetch(ctx context.Context, ids []string) (_ map[string]types.VectorRecord, finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "pinecone.fetch", attribute.Int("vector.count", len(ids)))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "fetch", start, finalErr, slog.Int("count", len(ids)))
+	}()
+
+	records := make(map[string]types.VectorRecord, len(ids))
+	for batch := range slices.Chunk(ids, PineconeBatchSize) {
+		vectors, err := callProvider(ctx, a.callOptions(true), types.ProviderPinecone, "fetch", func(ctx context.Context) (map[string]*pinecone.Vector, error) {
+			return a.index.Fetch(ctx, batch)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for id, vector := range vectors {
+			if vector == nil {
+				continue
+			}
+			metadata := make(map[string]any)
+			if vector.Metadata != nil {
+				metadata = vector.Metadata.AsMap()
+			}
+			records[id] = types.VectorRecord{ID: id, Vector: vector.Values, Metadata: metadata}
+		}
+	}
+
+	return records, nil
+}
+
+// UpdateMetadata implements ExtendedVectorClient interface. The given fields are set on the
+// vector; fields not in metadata are left unchanged.
+func (a *PineconeVectorAdapter) UpdateMetadata(ctx context.Context, id string, metadata map[string]any) (finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "pinecone.update_metadata")
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "update_metadata", start, finalErr, slog.String("id", id))
+	}()
+
 	metadataStruct, err := structpb.NewStruct(metadata)
 	if err != nil {
 		return err
 	}
 
-	vectors := []pinecone.Vector{
-		{
-			Id:     id,
-			Values: vector,
-			Metadata: &pinecone.Metadata{
-				Fields: metadataStruct.Fields,
-			},
-		},
+	_, err = callProvider(ctx, a.callOptions(false), types.ProviderPinecone, "update", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, a.index.UpdateMetadata(ctx, id, &pinecone.Metadata{Fields: metadataStruct.Fields})
+	})
+	return err
+}
+
+// Delete implements ExtendedVectorClient interface. Deleting IDs that aren't stored is not an error.
+func (a *PineconeVectorAdapter) Delete(ctx context.Context, ids []string) (finalErr error) {
+	ctx, span := tracing.Start(ctx, a.tracer, "pinecone.delete", attribute.Int("vector.count", len(ids)))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, a.logger, "delete", start, finalErr, slog.Int("count", len(ids)))
+	}()
+
+	for batch := range slices.Chunk(ids, PineconeBatchSize) {
+		_, err := callProvider(ctx, a.callOptions(false), types.ProviderPinecone, "delete", func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, a.index.Delete(ctx, batch)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callOptions returns the retry, timeout and circuit breaker settings for Pinecone requests.
+// Only reads are hedged.
+func (a *PineconeVectorAdapter) callOptions(read bool) callOptions {
+	opts := callOptions{
+		retry:   a.retryConfig,
+		timeout: a.timeout,
+		breaker: a.breaker,
+		limiter: a.limiter,
+		metrics: a.metrics,
+		tracer:  a.tracer,
+		logger:  a.logger,
 	}
+	if read {
+		opts.hedger = a.hedger
+	}
+	return opts
+}
 
-	return a.index.Upsert(ctx, vectors)
+// logCall logs an adapter call at debug level, or at warn level if it failed. Does nothing if logger is nil.
+func logCall(ctx context.Context, logger *slog.Logger, stage string, start time.Time, err error, attrs ...slog.Attr) {
+	if logger == nil {
+		return
+	}
+
+	attrs = append(attrs, slog.String("stage", stage), slog.Duration("latency", time.Since(start)))
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelWarn, "adapter call failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "adapter call completed", attrs...)
+}
+
+// checkDimensions returns an error matching types.ErrDimensionMismatch if vector doesn't have
+// the expected dimension. An expected dimension of 0 accepts any vector.
+func checkDimensions(vector []float32, expected int) error {
+	if expected != 0 && len(vector) != expected {
+		return fmt.Errorf("%w: expected %d, got %d", types.ErrDimensionMismatch, expected, len(vector))
+	}
+	return nil
 }
 
 // loadEnvVar loads an environment variable into a pointer if no value is provided
@@ -0,0 +1,137 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/FrenchMajesty/consistent-classifier/adapters/openai"
+	"github.com/FrenchMajesty/consistent-classifier/adapters/voyage"
+	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newProviderError wraps err in a ProviderError for the given provider and operation.
+// Errors that already carry a ProviderError are returned unchanged.
+func newProviderError(provider string, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var providerErr *types.ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+
+	statusCode := providerStatusCode(provider, err)
+	var exhaustedErr *retry.RetryExhaustedError
+	if statusCode == 0 && errors.As(err, &exhaustedErr) {
+		statusCode = exhaustedErr.LastStatusCode
+	}
+
+	retryable := isRetryable(err, statusCode)
+	var chatErr *openai.ChatCompletionError
+	if errors.As(err, &chatErr) && chatErr.Code != openai.ErrorCodeUnknown {
+		retryable = chatErr.Retryable
+	}
+
+	return &types.ProviderError{
+		Provider:   provider,
+		Op:         op,
+		StatusCode: statusCode,
+		Retryable:  retryable,
+		Err:        err,
+	}
+}
+
+// providerStatusCode extracts the HTTP status code from a provider's error, or 0 if it has none
+func providerStatusCode(provider string, err error) int {
+	switch provider {
+	case types.ProviderOpenAI:
+		return openAIStatusCode(err)
+	case types.ProviderVoyage:
+		return voyageStatusCode(err)
+	case types.ProviderPinecone:
+		return pineconeStatusCode(err)
+	default:
+		return 0
+	}
+}
+
+// isRetryable reports whether a failed call may succeed later: rate limits, server errors and network failures
+func isRetryable(err error, statusCode int) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch {
+	case statusCode == 0:
+		// Without a response, only failures to reach the provider are worth another attempt
+		return retry.IsTransportError(err)
+	case statusCode == http.StatusTooManyRequests:
+		return true
+	case statusCode >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// openAIStatusCode extracts the HTTP status code from an OpenAI client error
+func openAIStatusCode(err error) int {
+	var chatErr *openai.ChatCompletionError
+	if errors.As(err, &chatErr) {
+		return chatErr.StatusCode
+	}
+
+	var exhaustedErr *retry.RetryExhaustedError
+	if errors.As(err, &exhaustedErr) {
+		return exhaustedErr.LastStatusCode
+	}
+
+	return 0
+}
+
+// voyageStatusCode extracts the HTTP status code from a Voyage client error
+func voyageStatusCode(err error) int {
+	var apiErr *voyage.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// pineconeStatusCode maps the gRPC status of a Pinecone error to the equivalent HTTP status code
+func pineconeStatusCode(err error) int {
+	s, ok := status.FromError(err)
+	if !ok {
+		return 0
+	}
+
+	switch s.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return http.StatusInternalServerError
+	default:
+		return 0
+	}
+}
@@ -2,11 +2,26 @@ package adapters
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/FrenchMajesty/consistent-classifier/adapters/openai"
+	"github.com/FrenchMajesty/consistent-classifier/adapters/pinecone"
+	"github.com/FrenchMajesty/consistent-classifier/adapters/voyage"
+	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // Tests for unexported functions and internal behavior
@@ -238,3 +253,752 @@ func (m *mockLLMOpenAIClient) SetBaseURL(baseUrl string) {
 		m.setBaseURLFunc(baseUrl)
 	}
 }
+
+func TestDefaultLLMClient_RenderUserPrompt_Internal(t *testing.T) {
+	client := &DefaultLLMClient{systemPrompt: defaultSystemPrompt}
+
+	t.Run("plain text is sent unchanged", func(t *testing.T) {
+		rendered, err := client.renderUserPrompt(types.PromptData{Text: "hello there"})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if rendered != "hello there" {
+			t.Errorf("Expected raw text, got %q", rendered)
+		}
+	})
+
+	t.Run("labels and examples are included", func(t *testing.T) {
+		rendered, err := client.renderUserPrompt(types.PromptData{
+			Text:     "refund please",
+			Labels:   []string{"refund_request", "greeting"},
+			Examples: []types.LabeledExample{{Text: "give me my money back", Label: "refund_request"}},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := "Known labels (reuse one of these if it fits the text):\n" +
+			"- refund_request\n" +
+			"- greeting\n" +
+			"\n" +
+			"Examples of previously classified texts:\n" +
+			"Text: give me my money back\n" +
+			"Label: refund_request\n" +
+			"\n" +
+			"Text to classify:\n" +
+			"refund please"
+		if rendered != expected {
+			t.Errorf("Unexpected rendered prompt:\n%s", rendered)
+		}
+	})
+}
+
+func TestDefaultLLMClient_SetPromptTemplate_Internal(t *testing.T) {
+	var sentUserMessage string
+	responseContent := "custom_label"
+	mockClient := &mockLLMOpenAIClient{
+		chatCompletionFunc: func(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+			sentUserMessage = *req.Messages[1].Content
+			return &openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatMessage{Content: &responseContent}},
+				},
+			}, nil
+		},
+	}
+
+	client := &DefaultLLMClient{
+		client:       mockClient,
+		systemPrompt: defaultSystemPrompt,
+	}
+
+	if err := client.SetPromptTemplate("{{len .Labels}} labels | {{.Text}}"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_, err := client.ClassifyWithPrompt(context.Background(), types.PromptData{
+		Text:   "input",
+		Labels: []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if sentUserMessage != "2 labels | input" {
+		t.Errorf("Expected rendered custom template, got %q", sentUserMessage)
+	}
+
+	if err := client.SetPromptTemplate("{{.Text"); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
+
+func TestDefaultLLMClient_ProposeParent_Internal(t *testing.T) {
+	var sentSystem, sentUser string
+	responseContent := " Billing "
+	mockClient := &mockLLMOpenAIClient{
+		chatCompletionFunc: func(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+			sentSystem = *req.Messages[0].Content
+			sentUser = *req.Messages[1].Content
+			return &openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatMessage{Content: &responseContent}},
+				},
+			}, nil
+		},
+	}
+
+	client := &DefaultLLMClient{
+		client:       mockClient,
+		systemPrompt: defaultSystemPrompt,
+	}
+
+	parent, err := client.ProposeParent(context.Background(), "refund_request", []string{"billing", "social"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if parent != "billing" {
+		t.Errorf("Expected normalized parent 'billing', got '%s'", parent)
+	}
+
+	if sentSystem != parentCategoryPrompt {
+		t.Error("Expected parent category system prompt")
+	}
+
+	if sentUser != "Existing categories:\n- billing\n- social\n\nLabel: refund_request" {
+		t.Errorf("Unexpected user message: %q", sentUser)
+	}
+}
+
+func TestDefaultLLMClient_NameCluster_Internal(t *testing.T) {
+	var sentSystem, sentUser string
+	responseContent := "Refund_Request"
+	mockClient := &mockLLMOpenAIClient{
+		chatCompletionFunc: func(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+			sentSystem = *req.Messages[0].Content
+			sentUser = *req.Messages[1].Content
+			return &openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatMessage{Content: &responseContent}},
+				},
+			}, nil
+		},
+	}
+
+	client := &DefaultLLMClient{
+		client:       mockClient,
+		systemPrompt: defaultSystemPrompt,
+	}
+
+	name, err := client.NameCluster(context.Background(), []string{"money_back", "refund"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if name != "refund_request" {
+		t.Errorf("Expected normalized name 'refund_request', got '%s'", name)
+	}
+
+	if sentSystem != clusterNamePrompt {
+		t.Error("Expected cluster name system prompt")
+	}
+
+	if sentUser != "Labels:\n- money_back\n- refund\n" {
+		t.Errorf("Unexpected user message: %q", sentUser)
+	}
+}
+
+func TestNewProviderError(t *testing.T) {
+	exhausted := &retry.RetryExhaustedError{
+		APIName:        "OpenAI chat",
+		LastStatusCode: 429,
+		Err:            &openai.ChatCompletionError{Message: "rate limited", StatusCode: 429},
+	}
+
+	tests := []struct {
+		name          string
+		provider      string
+		err           error
+		wantStatus    int
+		wantRetryable bool
+	}{
+		{"openai retries exhausted", types.ProviderOpenAI, exhausted, 429, true},
+		{"openai bad request", types.ProviderOpenAI, &openai.ChatCompletionError{StatusCode: 401}, 401, false},
+		{"network error", types.ProviderOpenAI, fmt.Errorf("failed to read response: %w", syscall.ECONNRESET), 0, true},
+		{"decode error", types.ProviderVoyage, fmt.Errorf("failed to parse embedding response: %w", &json.SyntaxError{}), 0, false},
+		{"context canceled", types.ProviderOpenAI, context.Canceled, 0, false},
+		{"voyage rate limit", types.ProviderVoyage, fmt.Errorf("could not get embedding: %w", &voyage.APIError{StatusCode: 429}), 429, true},
+		{"voyage bad request", types.ProviderVoyage, &voyage.APIError{StatusCode: 400}, 400, false},
+		{"pinecone unavailable", types.ProviderPinecone, status.Error(codes.Unavailable, "down"), 503, true},
+		{"pinecone invalid argument", types.ProviderPinecone, status.Error(codes.InvalidArgument, "bad vector"), 400, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newProviderError(tt.provider, "op", tt.err)
+
+			var providerErr *types.ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("Expected ProviderError, got %T", err)
+			}
+			if providerErr.Provider != tt.provider || providerErr.StatusCode != tt.wantStatus || providerErr.Retryable != tt.wantRetryable {
+				t.Errorf("Unexpected ProviderError: %+v", providerErr)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("Expected ProviderError to unwrap to the original error")
+			}
+		})
+	}
+
+	t.Run("original error types are preserved", func(t *testing.T) {
+		err := newProviderError(types.ProviderOpenAI, "chat", exhausted)
+
+		var exhaustedErr *retry.RetryExhaustedError
+		var chatErr *openai.ChatCompletionError
+		if !errors.As(err, &exhaustedErr) || !errors.As(err, &chatErr) {
+			t.Errorf("Expected RetryExhaustedError and ChatCompletionError to be reachable, got %v", err)
+		}
+	})
+
+	t.Run("does not double wrap", func(t *testing.T) {
+		inner := newProviderError(types.ProviderVoyage, "embed", errors.New("boom"))
+		if err := newProviderError(types.ProviderOpenAI, "chat", inner); err != inner {
+			t.Errorf("Expected existing ProviderError to be returned unchanged, got %v", err)
+		}
+	})
+}
+
+// fakePineconeIndex is a Pinecone index stub for adapter tests
+type fakePineconeIndex struct {
+	searchErr   error
+	failures    int           // If > 0, only the first failures searches return searchErr
+	searchDelay time.Duration // Delays each search, returning early if ctx is done
+	searches    int
+	lastFilter  map[string]any
+	stored      map[string]*pinecone.Vector // Returned by Fetch
+	upserts     [][]pinecone.Vector         // Each upsert request
+	fetches     [][]string                  // Each fetch request
+	deletes     [][]string                  // Each delete request
+	updated     map[string]*pinecone.Metadata
+	dimension   int   // Returned by Dimension
+	describeErr error // Returned by Dimension if set
+	describes   int
+}
+
+func (f *fakePineconeIndex) Search(ctx context.Context, queryVector []float32, topK int, filter map[string]any, includeMetadata bool) ([]pinecone.QueryMatch, error) {
+	f.searches++
+	f.lastFilter = filter
+	if f.searchDelay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.searchDelay):
+		}
+	}
+	if f.failures > 0 && f.searches > f.failures {
+		return nil, nil
+	}
+	return nil, f.searchErr
+}
+
+func (f *fakePineconeIndex) Upsert(ctx context.Context, vectors []pinecone.Vector) error {
+	f.upserts = append(f.upserts, vectors)
+	return nil
+}
+
+func (f *fakePineconeIndex) Fetch(ctx context.Context, ids []string) (map[string]*pinecone.Vector, error) {
+	f.fetches = append(f.fetches, ids)
+	vectors := make(map[string]*pinecone.Vector)
+	for _, id := range ids {
+		if vector, ok := f.stored[id]; ok {
+			vectors[id] = vector
+		}
+	}
+	return vectors, nil
+}
+
+func (f *fakePineconeIndex) UpdateMetadata(ctx context.Context, vectorID string, metadata *pinecone.Metadata) error {
+	if f.updated == nil {
+		f.updated = make(map[string]*pinecone.Metadata)
+	}
+	f.updated[vectorID] = metadata
+	return nil
+}
+
+func (f *fakePineconeIndex) Dimension(ctx context.Context) (int, error) {
+	f.describes++
+	return f.dimension, f.describeErr
+}
+
+func (f *fakePineconeIndex) Delete(ctx context.Context, ids []string) error {
+	f.deletes = append(f.deletes, ids)
+	return nil
+}
+
+func TestPineconeVectorAdapter_CircuitBreaker(t *testing.T) {
+	index := &fakePineconeIndex{searchErr: status.Error(codes.Unavailable, "down")}
+	adapter := &PineconeVectorAdapter{index: index}
+	adapter.SetCircuitBreaker(NewCircuitBreaker("Pinecone", CircuitBreakerConfig{
+		Window:      time.Minute,
+		MinRequests: 2,
+		FailureRate: 0.5,
+		CoolDown:    time.Minute,
+	}))
+
+	for range 2 {
+		if _, err := adapter.Search(context.Background(), []float32{0.1}, 1); err == nil {
+			t.Fatal("Expected search error")
+		}
+	}
+
+	_, err := adapter.Search(context.Background(), []float32{0.1}, 1)
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Provider != types.ProviderPinecone {
+		t.Fatalf("Expected Pinecone ProviderError, got %v", err)
+	}
+	if !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("Expected circuit open error, got %v", err)
+	}
+	if index.searches != 2 {
+		t.Errorf("Expected open breaker to skip the search, got %d searches", index.searches)
+	}
+}
+
+func TestPineconeVectorAdapter_CircuitBreakerIgnoresBadRequests(t *testing.T) {
+	index := &fakePineconeIndex{searchErr: status.Error(codes.InvalidArgument, "bad vector")}
+	adapter := &PineconeVectorAdapter{index: index}
+	adapter.SetCircuitBreaker(NewCircuitBreaker("Pinecone", CircuitBreakerConfig{
+		Window:      time.Minute,
+		MinRequests: 1,
+		FailureRate: 0.5,
+		CoolDown:    time.Minute,
+	}))
+
+	for range 3 {
+		_, err := adapter.Search(context.Background(), []float32{0.1}, 1)
+		if errors.Is(err, retry.ErrCircuitOpen) {
+			t.Fatal("Expected bad requests not to trip the breaker")
+		}
+	}
+}
+
+func TestPineconeVectorAdapter_CircuitBreakerReleasesAbandonedProbe(t *testing.T) {
+	index := &fakePineconeIndex{searchErr: status.Error(codes.Unavailable, "down")}
+	adapter := &PineconeVectorAdapter{index: index}
+	breaker := NewCircuitBreaker("Pinecone", CircuitBreakerConfig{Window: time.Minute, MinRequests: 1, FailureRate: 0.5})
+	adapter.SetCircuitBreaker(breaker)
+
+	adapter.Search(context.Background(), []float32{0.1}, 1)
+	if breaker.State() != retry.BreakerHalfOpen {
+		t.Fatalf("Expected the breaker to trip straight to half-open, got %s", breaker.State())
+	}
+
+	// The probe outlives the caller's deadline, which says nothing about whether Pinecone recovered
+	index.searchDelay = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := adapter.Search(ctx, []float32{0.1}, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the caller's deadline, got %v", err)
+	}
+	if breaker.State() != retry.BreakerHalfOpen {
+		t.Errorf("Expected an abandoned probe to leave the breaker half-open, got %s", breaker.State())
+	}
+
+	// The released probe slot goes to the next call
+	index.searchDelay = 0
+	if _, err := adapter.Search(context.Background(), []float32{0.1}, 1); errors.Is(err, retry.ErrCircuitOpen) {
+		t.Errorf("Expected the next call to be let through as the probe, got %v", err)
+	}
+}
+
+func TestPineconeVectorAdapter_RetriesTransientErrors(t *testing.T) {
+	index := &fakePineconeIndex{searchErr: status.Error(codes.Unavailable, "down"), failures: 2}
+	adapter := &PineconeVectorAdapter{index: index, retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	if _, err := adapter.Search(context.Background(), []float32{0.1}, 1); err != nil {
+		t.Fatalf("Expected search to succeed after retries, got %v", err)
+	}
+	if index.searches != 3 {
+		t.Errorf("Expected 3 searches, got %d", index.searches)
+	}
+}
+
+func TestPineconeVectorAdapter_DoesNotRetryBadRequests(t *testing.T) {
+	index := &fakePineconeIndex{searchErr: status.Error(codes.InvalidArgument, "bad vector")}
+	adapter := &PineconeVectorAdapter{index: index, retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	_, err := adapter.Search(context.Background(), []float32{0.1}, 1)
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != 400 {
+		t.Fatalf("Expected ProviderError with status 400, got %v", err)
+	}
+	if index.searches != 1 {
+		t.Errorf("Expected 1 search, got %d", index.searches)
+	}
+}
+
+func TestPineconeVectorAdapter_DoesNotRetryLocalErrors(t *testing.T) {
+	// A response that can't be decoded fails the same way on every attempt
+	index := &fakePineconeIndex{searchErr: fmt.Errorf("failed to decode match metadata: %w", &json.SyntaxError{})}
+	adapter := &PineconeVectorAdapter{index: index, retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	_, err := adapter.Search(context.Background(), []float32{0.1}, 1)
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Retryable {
+		t.Fatalf("Expected a non-retryable ProviderError, got %v", err)
+	}
+	if index.searches != 1 {
+		t.Errorf("Expected 1 search, got %d", index.searches)
+	}
+}
+
+func TestPineconeVectorAdapter_AttemptTimeout(t *testing.T) {
+	index := &fakePineconeIndex{searchDelay: time.Second}
+	adapter := &PineconeVectorAdapter{
+		index:       index,
+		retryConfig: RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		timeout:     10 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := adapter.Search(context.Background(), []float32{0.1}, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if index.searches != 2 {
+		t.Errorf("Expected timed out attempt to be retried, got %d searches", index.searches)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected attempts to be bounded by the timeout, took %v", elapsed)
+	}
+}
+
+func TestPineconeVectorAdapter_CallerCancellation(t *testing.T) {
+	index := &fakePineconeIndex{searchDelay: time.Second}
+	adapter := &PineconeVectorAdapter{index: index, retryConfig: RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := adapter.Search(ctx, []float32{0.1}, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if index.searches != 1 {
+		t.Errorf("Expected no retries once the caller gave up, got %d searches", index.searches)
+	}
+}
+
+func TestVoyageEmbeddingAdapter_RetriesRateLimits(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","embedding":[0.1,0.2],"index":0}],"model":"voyage-3.5"}`)
+	}))
+	defer server.Close()
+
+	service := voyage.NewEmbeddingService("test-key")
+	service.SetBaseURL(server.URL)
+	adapter := &VoyageEmbeddingAdapter{client: service, retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	embedding, err := adapter.GenerateEmbedding(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Expected embedding after retry, got %v", err)
+	}
+	if len(embedding) != 2 {
+		t.Errorf("Expected 2 dimensions, got %d", len(embedding))
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestPineconeVectorAdapter_HedgesSlowSearches(t *testing.T) {
+	index := &slowFirstPineconeIndex{delay: time.Second}
+	adapter := &PineconeVectorAdapter{index: index}
+	adapter.SetHedger(NewHedger(HedgeConfig{MaxDelay: 10 * time.Millisecond}))
+	recorder := &hedgeCountingRecorder{}
+	adapter.SetMetricsRecorder(recorder)
+
+	start := time.Now()
+	if _, err := adapter.Search(context.Background(), []float32{0.1}, 1); err != nil {
+		t.Fatalf("Expected hedged search to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the hedge to return before the slow search, took %v", elapsed)
+	}
+	if recorder.won.Load() != 1 {
+		t.Errorf("Expected 1 winning hedge recorded, got %d", recorder.won.Load())
+	}
+
+	// Upserts are never hedged, however slow
+	if err := adapter.Upsert(context.Background(), "id", []float32{0.1}, map[string]any{}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if index.upserts.Load() != 1 {
+		t.Errorf("Expected 1 upsert, got %d", index.upserts.Load())
+	}
+}
+
+// slowFirstPineconeIndex is a Pinecone index stub whose first search is slow
+type slowFirstPineconeIndex struct {
+	fakePineconeIndex
+	delay   time.Duration
+	calls   atomic.Int32
+	upserts atomic.Int32
+}
+
+func (f *slowFirstPineconeIndex) Search(ctx context.Context, queryVector []float32, topK int, filter map[string]any, includeMetadata bool) ([]pinecone.QueryMatch, error) {
+	if f.calls.Add(1) == 1 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.delay):
+		}
+	}
+	return nil, nil
+}
+
+func (f *slowFirstPineconeIndex) Upsert(ctx context.Context, vectors []pinecone.Vector) error {
+	f.upserts.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+// hedgeCountingRecorder counts hedges that returned first
+type hedgeCountingRecorder struct {
+	types.NopMetricsRecorder
+	won atomic.Int32
+}
+
+func (r *hedgeCountingRecorder) RecordHedge(api string, won bool) {
+	if won {
+		r.won.Add(1)
+	}
+}
+
+func TestVoyageEmbeddingAdapter_RateLimited(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","embedding":[0.1],"index":0}],"model":"voyage-3.5"}`)
+	}))
+	defer server.Close()
+
+	service := voyage.NewEmbeddingService("test-key")
+	service.SetBaseURL(server.URL)
+	adapter := &VoyageEmbeddingAdapter{client: service}
+	adapter.SetRateLimiter(NewRateLimiter(RateLimitConfig{MaxInFlight: 2}))
+
+	errs := make(chan error, 8)
+	for range 8 {
+		go func() {
+			_, err := adapter.GenerateEmbedding(context.Background(), "hello")
+			errs <- err
+		}()
+	}
+	for range 8 {
+		if err := <-errs; err != nil {
+			t.Fatalf("GenerateEmbedding failed: %v", err)
+		}
+	}
+
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", peak.Load())
+	}
+}
+
+func TestVoyageEmbeddingAdapter_GenerateEmbeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Out of order, to check results are matched by index
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","embedding":[0.2],"index":1},{"object":"embedding","embedding":[0.1],"index":0}],"model":"voyage-3.5"}`)
+	}))
+	defer server.Close()
+
+	service := voyage.NewEmbeddingService("test-key")
+	service.SetBaseURL(server.URL)
+	adapter := &VoyageEmbeddingAdapter{client: service}
+
+	embeddings, err := adapter.GenerateEmbeddings(context.Background(), []string{"first", "second"}, types.EmbeddingInputDocument)
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings failed: %v", err)
+	}
+	if len(embeddings) != 2 || embeddings[0][0] != 0.1 || embeddings[1][0] != 0.2 {
+		t.Errorf("Expected embeddings in input order, got %v", embeddings)
+	}
+
+	if _, err := adapter.GenerateEmbeddings(context.Background(), []string{"first", "second", "third"}, types.EmbeddingInputDocument); err == nil {
+		t.Error("Expected error when an embedding is missing")
+	}
+}
+
+func TestVoyageEmbeddingAdapter_InputTypeAndDimensions(t *testing.T) {
+	var inputTypes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			InputType *string `json:"input_type"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request.InputType != nil {
+			inputTypes = append(inputTypes, *request.InputType)
+		} else {
+			inputTypes = append(inputTypes, "")
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","embedding":[0.1,0.2],"index":0}]}`)
+	}))
+	defer server.Close()
+
+	service := voyage.NewEmbeddingService("test-key")
+	service.SetBaseURL(server.URL)
+	adapter := &VoyageEmbeddingAdapter{client: service, inputType: types.EmbeddingInputDocument, dimensions: 2}
+
+	if _, err := adapter.GenerateEmbedding(context.Background(), "text"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if _, err := adapter.GenerateEmbeddingForInput(context.Background(), "text", types.EmbeddingInputQuery); err != nil {
+		t.Fatalf("GenerateEmbeddingForInput failed: %v", err)
+	}
+	if strings.Join(inputTypes, ",") != "document,query" {
+		t.Errorf("Expected the configured then the requested input type, got %v", inputTypes)
+	}
+
+	adapter.dimensions = 1024
+	_, err := adapter.GenerateEmbedding(context.Background(), "text")
+	if !errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected dimension mismatch, got %v", err)
+	}
+}
+
+func TestPineconeVectorAdapter_DimensionMismatch(t *testing.T) {
+	index := &fakePineconeIndex{}
+	adapter := &PineconeVectorAdapter{index: index, dimension: 3}
+
+	if _, err := adapter.Search(context.Background(), []float32{0.1}, 1); !errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected dimension mismatch on search, got %v", err)
+	}
+	if err := adapter.Upsert(context.Background(), "id", []float32{0.1}, map[string]any{}); !errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected dimension mismatch on upsert, got %v", err)
+	}
+	if index.searches != 0 {
+		t.Errorf("Expected mismatched vectors not to reach Pinecone, got %d searches", index.searches)
+	}
+
+	if _, err := adapter.Search(context.Background(), []float32{0.1, 0.2, 0.3}, 1); err != nil {
+		t.Errorf("Expected matching vector to be searched, got %v", err)
+	}
+}
+
+func TestPineconeVectorAdapter_MatchDimension(t *testing.T) {
+	index := &fakePineconeIndex{dimension: 1024}
+	adapter := &PineconeVectorAdapter{index: index}
+
+	if err := adapter.MatchDimension(context.Background(), 1024); err != nil {
+		t.Errorf("Expected matching dimensions to pass, got %v", err)
+	}
+	if err := adapter.MatchDimension(context.Background(), 512); !errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected dimension mismatch against the index, got %v", err)
+	}
+	if _, err := adapter.Search(context.Background(), make([]float32, 1024), 1); !errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected vectors to be checked against the matched dimension, got %v", err)
+	}
+
+	index.describeErr = status.Error(codes.PermissionDenied, "forbidden")
+	if err := adapter.MatchDimension(context.Background(), 1024); err == nil || errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected the describe error, got %v", err)
+	}
+
+	// An unavailable index fails the check at once rather than being retried
+	adapter.retryConfig = RetryConfig{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Second}
+	index.describeErr = status.Error(codes.Unavailable, "down")
+	index.describes = 0
+	if err := adapter.MatchDimension(context.Background(), 1024); err == nil || index.describes != 1 {
+		t.Errorf("Expected one failed describe, got %d describes and %v", index.describes, err)
+	}
+}
+
+func TestPineconeVectorAdapter_ExtendedOperations(t *testing.T) {
+	metadata, _ := structpb.NewStruct(map[string]any{"label": "greeting"})
+	index := &fakePineconeIndex{
+		stored: map[string]*pinecone.Vector{
+			"a": {Id: "a", Values: []float32{0.1, 0.2}, Metadata: &pinecone.Metadata{Fields: metadata.Fields}},
+		},
+	}
+	adapter := &PineconeVectorAdapter{index: index, dimension: 2}
+	ctx := context.Background()
+
+	filter := map[string]any{"tenant": map[string]any{"$eq": "acme"}}
+	if _, err := adapter.SearchWithFilter(ctx, []float32{0.1, 0.2}, 1, filter); err != nil {
+		t.Fatalf("SearchWithFilter failed: %v", err)
+	}
+	if !reflect.DeepEqual(index.lastFilter, filter) {
+		t.Errorf("Expected filter to be passed to the index, got %v", index.lastFilter)
+	}
+
+	records, err := adapter.Fetch(ctx, []string{"a", "missing"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(records) != 1 || records["a"].Metadata["label"] != "greeting" || len(records["a"].Vector) != 2 {
+		t.Errorf("Expected only the stored vector with its metadata, got %+v", records)
+	}
+
+	if err := adapter.UpdateMetadata(ctx, "a", map[string]any{"root": "hello"}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if index.updated["a"].AsMap()["root"] != "hello" {
+		t.Errorf("Expected metadata update to be sent, got %v", index.updated["a"])
+	}
+
+	ids := make([]string, PineconeBatchSize+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id-%d", i)
+	}
+	if err := adapter.Delete(ctx, ids); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(index.deletes) != 2 || len(index.deletes[0]) != PineconeBatchSize || len(index.deletes[1]) != 1 {
+		t.Errorf("Expected deletes split into batches of %d, got %d requests", PineconeBatchSize, len(index.deletes))
+	}
+}
+
+func TestPineconeVectorAdapter_BatchUpsert(t *testing.T) {
+	index := &fakePineconeIndex{}
+	adapter := &PineconeVectorAdapter{index: index, dimension: 2}
+
+	records := make([]types.VectorRecord, PineconeBatchSize+1)
+	for i := range records {
+		records[i] = types.VectorRecord{ID: fmt.Sprintf("id-%d", i), Vector: []float32{0.1, 0.2}, Metadata: map[string]any{"label": "greeting"}}
+	}
+	if err := adapter.BatchUpsert(context.Background(), records); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+	if len(index.upserts) != 2 || len(index.upserts[0]) != PineconeBatchSize || len(index.upserts[1]) != 1 {
+		t.Errorf("Expected upserts split into batches of %d, got %d requests", PineconeBatchSize, len(index.upserts))
+	}
+
+	// A mismatched vector fails the whole call before anything is sent
+	index.upserts = nil
+	records[1].Vector = []float32{0.1}
+	if err := adapter.BatchUpsert(context.Background(), records); !errors.Is(err, types.ErrDimensionMismatch) {
+		t.Errorf("Expected dimension mismatch, got %v", err)
+	}
+	if len(index.upserts) != 0 {
+		t.Errorf("Expected no upserts, got %d", len(index.upserts))
+	}
+}
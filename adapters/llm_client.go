@@ -1,20 +1,31 @@
 package adapters
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/FrenchMajesty/consistent-classifier/adapters/openai"
+	"github.com/FrenchMajesty/consistent-classifier/internal/tracing"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DefaultLLMClient implements LLMClient using OpenAI
 type DefaultLLMClient struct {
 	client       openai.LanguageModelClient
 	systemPrompt string
+	userPrompt   *template.Template // Optional user message template. If nil, uses defaultUserPrompt.
 	model        string
 	baseUrl      string
 	temperature  *float32 // Optional temperature. If nil, omit from request.
+	metrics      types.MetricsRecorder
+	tracer       trace.Tracer
+	logger       *slog.Logger
 }
 
 const defaultModel = "gpt-4.1-mini"
@@ -26,6 +37,36 @@ Rules:
 - Keep labels short and descriptive (2-5 words max)
 - Be consistent: similar texts should get the same label`
 
+// defaultUserPrompt renders the raw text when no labels or examples are provided,
+// so the plain Classify path sends exactly the input text
+const defaultUserPrompt = `{{- if .Labels}}Known labels (reuse one of these if it fits the text):
+{{range .Labels}}- {{.}}
+{{end}}
+{{end}}
+{{- if .Examples}}Examples of previously classified texts:
+{{range .Examples}}Text: {{.Text}}
+Label: {{.Label}}
+
+{{end}}{{end}}
+{{- if or .Labels .Examples}}Text to classify:
+{{end}}{{.Text}}`
+
+const parentCategoryPrompt = `You group classification labels into broad parent categories. Given a label, return the coarse category it belongs to.
+
+Rules:
+- Return ONLY the category, nothing else
+- Use lowercase with underscores (e.g., "billing", "account_management")
+- Reuse one of the existing categories if it fits`
+
+const clusterNamePrompt = `You name clusters of classification labels that mean the same thing. Given the labels in a cluster, return one label that best summarises them.
+
+Rules:
+- Return ONLY the label, nothing else
+- Use lowercase with underscores (e.g., "refund_request", "technical_support")
+- Prefer one of the given labels if it already fits`
+
+var defaultUserTemplate = template.Must(template.New("user").Parse(defaultUserPrompt))
+
 // NewDefaultLLMClient creates a new LLM client using OpenAI with API key from environment
 func NewDefaultLLMClient(apiKey *string, systemPrompt string, model string, baseUrl string, temperature *float32) (*DefaultLLMClient, error) {
 	key, err := loadEnvVar(apiKey, "OPENAI_API_KEY")
@@ -52,18 +93,137 @@ func NewDefaultLLMClient(apiKey *string, systemPrompt string, model string, base
 	return &instance, nil
 }
 
+// SetPromptTemplate sets the text/template used to render the user message.
+// The template is executed with a types.PromptData, exposing .Text, .Labels and .Examples.
+func (c *DefaultLLMClient) SetPromptTemplate(tmpl string) error {
+	parsed, err := template.New("user").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	c.userPrompt = parsed
+	return nil
+}
+
+// SetMetricsRecorder reports token usage, retry and hedge counts and rate limit waits to the given recorder
+func (c *DefaultLLMClient) SetMetricsRecorder(recorder types.MetricsRecorder) {
+	c.metrics = recorder
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.Metrics = recorder
+	}
+}
+
+// SetTracerProvider creates spans for LLM calls, retry attempts and HTTP requests using the given provider
+func (c *DefaultLLMClient) SetTracerProvider(tp trace.TracerProvider) {
+	c.tracer = tracing.Tracer(tp)
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.Tracer = c.tracer
+	}
+}
+
+// SetLogger logs LLM calls, retries and request dumps to the given logger
+func (c *DefaultLLMClient) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.Logger = logger
+	}
+}
+
+// SetRetryBudget limits retries using a budget that can be shared with other clients
+func (c *DefaultLLMClient) SetRetryBudget(budget *RetryBudget) {
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.RetryBudget = budget
+	}
+}
+
+// SetCircuitBreaker replaces the circuit breaker guarding OpenAI requests. nil disables it.
+func (c *DefaultLLMClient) SetCircuitBreaker(breaker *CircuitBreaker) {
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.Breaker = breaker
+	}
+}
+
+// SetHedger hedges slow LLM requests. Only requests at temperature 0 are hedged, since others
+// aren't deterministic. nil, the default, disables hedging.
+func (c *DefaultLLMClient) SetHedger(hedger *Hedger) {
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.Hedger = hedger
+	}
+}
+
+// SetRateLimiter throttles OpenAI requests. Share the limiter between clients using the same API key.
+func (c *DefaultLLMClient) SetRateLimiter(limiter *RateLimiter) {
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.Limiter = limiter
+	}
+}
+
+// SetRetryJitter sets how retry delays are randomized. Defaults to RetryJitterFull.
+func (c *DefaultLLMClient) SetRetryJitter(jitter RetryJitter) {
+	if client, ok := c.client.(*openai.OpenAIClient); ok {
+		client.RetryConfig.Jitter = jitter
+	}
+}
+
 // Classify classifies text into a category label using LLM
 func (c *DefaultLLMClient) Classify(ctx context.Context, text string) (string, error) {
+	return c.ClassifyWithPrompt(ctx, types.PromptData{Text: text})
+}
+
+// ClassifyWithPrompt classifies text using the prompt template rendered with the given data
+func (c *DefaultLLMClient) ClassifyWithPrompt(ctx context.Context, data types.PromptData) (string, error) {
+	userMessage, err := c.renderUserPrompt(data)
+	if err != nil {
+		return "", err
+	}
+
+	return c.complete(ctx, c.systemPrompt, userMessage)
+}
+
+// ProposeParent asks the LLM for a coarse parent category of the label, preferring existing categories
+func (c *DefaultLLMClient) ProposeParent(ctx context.Context, label string, categories []string) (string, error) {
+	var userMessage strings.Builder
+	if len(categories) > 0 {
+		userMessage.WriteString("Existing categories:\n")
+		for _, category := range categories {
+			userMessage.WriteString("- " + category + "\n")
+		}
+		userMessage.WriteString("\n")
+	}
+	userMessage.WriteString("Label: " + label)
+
+	return c.complete(ctx, parentCategoryPrompt, userMessage.String())
+}
+
+// NameCluster asks the LLM for one label summarising a cluster of synonymous labels
+func (c *DefaultLLMClient) NameCluster(ctx context.Context, labels []string) (string, error) {
+	var userMessage strings.Builder
+	userMessage.WriteString("Labels:\n")
+	for _, label := range labels {
+		userMessage.WriteString("- " + label + "\n")
+	}
+
+	return c.complete(ctx, clusterNamePrompt, userMessage.String())
+}
+
+// complete sends a system and user message to the LLM and returns the normalized answer
+func (c *DefaultLLMClient) complete(ctx context.Context, systemPrompt string, userMessage string) (_ string, finalErr error) {
+	ctx, span := tracing.Start(ctx, c.tracer, "llm.complete", tracing.AttrModel.String(c.model))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, finalErr)
+		logCall(ctx, c.logger, "llm", start, finalErr, slog.String("model", c.model))
+	}()
+
 	req := openai.ChatCompletionRequest{
 		Model: c.model,
 		Messages: []openai.ChatMessage{
 			{
 				Role:    openai.MessageRoleSystem,
-				Content: &c.systemPrompt,
+				Content: &systemPrompt,
 			},
 			{
 				Role:    openai.MessageRoleUser,
-				Content: &text,
+				Content: &userMessage,
 			},
 		},
 		MaxCompletionTokens: 50,
@@ -72,11 +232,16 @@ func (c *DefaultLLMClient) Classify(ctx context.Context, text string) (string, e
 	// Only set temperature if specified (some models like gpt-5-nano don't support it)
 	if c.temperature != nil {
 		req.Temperature = *c.temperature
+		req.Idempotent = *c.temperature == 0
 	}
 
 	resp, err := c.client.ChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get LLM response: %w", err)
+		return "", fmt.Errorf("failed to get LLM response: %w", newProviderError(types.ProviderOpenAI, "chat", err))
+	}
+
+	if c.metrics != nil {
+		c.metrics.RecordTokenUsage(c.model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	}
 
 	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == nil {
@@ -85,6 +250,22 @@ func (c *DefaultLLMClient) Classify(ctx context.Context, text string) (string, e
 
 	label := strings.TrimSpace(*resp.Choices[0].Message.Content)
 	label = strings.ToLower(label)
+	span.SetAttributes(tracing.AttrLabel.String(label))
 
 	return label, nil
 }
+
+// renderUserPrompt executes the user message template with the given data
+func (c *DefaultLLMClient) renderUserPrompt(data types.PromptData) (string, error) {
+	tmpl := c.userPrompt
+	if tmpl == nil {
+		tmpl = defaultUserTemplate
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -11,7 +11,7 @@ import (
 
 func TestNewDefaultLLMClient_WithAPIKey(t *testing.T) {
 	apiKey := "test-openai-key"
-	client, err := adapters.NewDefaultLLMClient(&apiKey, "", "", "")
+	client, err := adapters.NewDefaultLLMClient(&apiKey, "", "", "", nil)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -25,7 +25,7 @@ func TestNewDefaultLLMClient_WithAPIKey(t *testing.T) {
 func TestNewDefaultLLMClient_FromEnv(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "env-openai-key")
 
-	client, err := adapters.NewDefaultLLMClient(nil, "", "", "")
+	client, err := adapters.NewDefaultLLMClient(nil, "", "", "", nil)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -39,7 +39,7 @@ func TestNewDefaultLLMClient_FromEnv(t *testing.T) {
 func TestNewDefaultLLMClient_MissingKey(t *testing.T) {
 	os.Unsetenv("OPENAI_API_KEY")
 
-	_, err := adapters.NewDefaultLLMClient(nil, "", "", "")
+	_, err := adapters.NewDefaultLLMClient(nil, "", "", "", nil)
 
 	if err == nil {
 		t.Error("Expected error when API key is missing, got nil")
@@ -50,7 +50,7 @@ func TestNewDefaultLLMClient_CustomPrompt(t *testing.T) {
 	apiKey := "test-key"
 	customPrompt := "You are a custom classifier"
 
-	client, err := adapters.NewDefaultLLMClient(&apiKey, customPrompt, "", "")
+	client, err := adapters.NewDefaultLLMClient(&apiKey, customPrompt, "", "", nil)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -64,7 +64,7 @@ func TestNewDefaultLLMClient_CustomPrompt(t *testing.T) {
 func TestNewDefaultLLMClient_DefaultPrompt(t *testing.T) {
 	apiKey := "test-key"
 
-	client, err := adapters.NewDefaultLLMClient(&apiKey, "", "", "")
+	client, err := adapters.NewDefaultLLMClient(&apiKey, "", "", "", nil)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+// ErrorCode categorizes an OpenAI API error
+type ErrorCode string
+
+const (
+	ErrorCodeUnknown        ErrorCode = ""
+	ErrorCodeRateLimit      ErrorCode = "rate_limit"
+	ErrorCodeQuotaExceeded  ErrorCode = "quota_exceeded"
+	ErrorCodeContextLength  ErrorCode = "context_length"
+	ErrorCodeInvalidRequest ErrorCode = "invalid_request"
+	ErrorCodeContentFilter  ErrorCode = "content_filter"
+	ErrorCodeServer         ErrorCode = "server"
+)
+
+// DefaultRetryPolicy retries rate limits and server errors. Requests that failed for any other
+// reason fail the same way when retried.
+func DefaultRetryPolicy() map[ErrorCode]bool {
+	return map[ErrorCode]bool{
+		ErrorCodeRateLimit: true,
+		ErrorCodeServer:    true,
+	}
+}
+
+// ClassifyError categorizes an error response from its status code and body
+func ClassifyError(statusCode int, responseBody []byte) ErrorCode {
+	var errorResp ChatCompletionResponseError
+	_ = json.Unmarshal(responseBody, &errorResp)
+	chatErr := errorResp.Error
+	message := strings.ToLower(chatErr.Message)
+
+	switch {
+	case chatErr.Code == "context_length_exceeded" || strings.Contains(message, "maximum context length"):
+		return ErrorCodeContextLength
+	case chatErr.Code == "content_filter" || chatErr.Code == "content_policy_violation" ||
+		strings.Contains(message, "content management policy"):
+		return ErrorCodeContentFilter
+	case chatErr.Code == "insufficient_quota" || chatErr.Type == "insufficient_quota":
+		return ErrorCodeQuotaExceeded
+	case statusCode == http.StatusTooManyRequests || chatErr.Code == "rate_limit_exceeded":
+		return ErrorCodeRateLimit
+	case statusCode >= 500 || chatErr.Type == "server_error" || chatErr.FailedGeneration != "" ||
+		strings.Contains(string(responseBody), "failed_generation"):
+		return ErrorCodeServer
+	case statusCode >= 400:
+		return ErrorCodeInvalidRequest
+	default:
+		return ErrorCodeUnknown
+	}
+}
+
+// sentinel returns the provider-agnostic error matching the code
+func (code ErrorCode) sentinel() error {
+	switch code {
+	case ErrorCodeRateLimit:
+		return types.ErrRateLimited
+	case ErrorCodeQuotaExceeded:
+		return types.ErrQuotaExceeded
+	case ErrorCodeContextLength:
+		return types.ErrContextLength
+	case ErrorCodeInvalidRequest:
+		return types.ErrInvalidRequest
+	case ErrorCodeContentFilter:
+		return types.ErrContentFilter
+	case ErrorCodeServer:
+		return types.ErrProviderServer
+	default:
+		return nil
+	}
+}
@@ -3,9 +3,13 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 
+	"github.com/FrenchMajesty/consistent-classifier/internal/ratelimit"
 	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // OpenAIClient is a minimal client for the OpenAI Chat API
@@ -16,6 +20,14 @@ type OpenAIClient struct {
 	BaseURL      string
 	HTTPClient   *http.Client
 	RetryConfig  retry.Config
+	RetryBudget  *retry.Budget         // Optional. Shared budget that limits retries across requests.
+	Breaker      *retry.Breaker        // Optional. Fails requests fast while OpenAI is unhealthy.
+	Hedger       *retry.Hedger         // Optional. Hedges slow chat requests marked Idempotent.
+	Limiter      *ratelimit.Limiter    // Optional. Throttles requests before they are sent.
+	RetryPolicy  map[ErrorCode]bool    // Which error codes are retried. If nil, uses DefaultRetryPolicy.
+	Metrics      types.MetricsRecorder // Optional. Receives retry and hedge counts and rate limit waits.
+	Tracer       trace.Tracer          // Optional. Creates spans for retry attempts and HTTP calls.
+	Logger       *slog.Logger          // Optional. Logs retries and request dumps. If nil, nothing is logged.
 }
 
 type LanguageModelClient interface {
@@ -36,6 +48,9 @@ type ChatCompletionRequest struct {
 	ResponseFormat      *ResponseFormat `json:"response_format,omitempty"`
 	ReasoningEffort     ReasoningEffort `json:"reasoning_effort,omitempty"`
 	Stream              bool            `json:"stream,omitempty"`
+
+	// Idempotent marks the request as safe to send twice, e.g. at temperature 0, so it may be hedged
+	Idempotent bool `json:"-"`
 }
 
 type ReasoningEffort string
@@ -107,13 +122,24 @@ type ChatCompletionResponseError struct {
 type ChatCompletionError struct {
 	Message    string          `json:"message"`
 	StatusCode int             `json:"status_code,omitempty"`
+	Code       ErrorCode       `json:"code,omitempty"`
+	Retryable  bool            `json:"retryable,omitempty"`
 	RawBody    json.RawMessage `json:"raw_body,omitempty"`
 }
 
 func (e *ChatCompletionError) Error() string {
+	if e.Code != ErrorCodeUnknown {
+		return e.Message + " (" + string(e.Code) + ")"
+	}
 	return e.Message
 }
 
+// Is matches the provider-agnostic error for the error code, e.g. types.ErrContextLength
+func (e *ChatCompletionError) Is(target error) bool {
+	sentinel := e.Code.sentinel()
+	return sentinel != nil && target == sentinel
+}
+
 // GetRawResponseBody returns the raw response body if available
 func (e *ChatCompletionError) GetRawResponseBody() json.RawMessage {
 	return e.RawBody
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
@@ -17,7 +18,9 @@ func NewClient(apiKey string) *OpenAIClient {
 		APIKey:      apiKey,
 		HTTPClient:  http.DefaultClient,
 		RetryConfig: retry.DefaultConfig(),
+		Breaker:     retry.NewBreaker("OpenAI", retry.DefaultBreakerConfig()),
 		BaseURL:     openaiBaseURL,
+		Logger:      slog.Default(),
 	}
 
 	return client
@@ -1,14 +1,23 @@
 package openai
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/FrenchMajesty/consistent-classifier/internal/ratelimit"
 	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewClient(t *testing.T) {
@@ -97,8 +106,8 @@ func TestChatCompletion_Success(t *testing.T) {
 	}
 
 	// Since we can't override the baseURL easily, we'll test the buildRetryableFn directly
-	retryableFn := client.buildRetryableFn(ctx, server.URL+"/chat/completions", req, "chat")
-	result, statusCode, bodyBytes, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL+"/chat/completions", req, "chat")
+	result, statusCode, _, bodyBytes, err := retryableFn(ctx, 0)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -154,8 +163,8 @@ func TestChatCompletion_HTTPError(t *testing.T) {
 		},
 	}
 
-	retryableFn := client.buildRetryableFn(ctx, server.URL, req, "chat")
-	_, statusCode, bodyBytes, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL, req, "chat")
+	_, statusCode, _, bodyBytes, err := retryableFn(ctx, 0)
 
 	if err == nil {
 		t.Error("Expected error for 500 status")
@@ -193,8 +202,8 @@ func TestChatCompletion_InvalidJSON(t *testing.T) {
 	}
 
 	// Test through the full ChatCompletion flow to test JSON parsing
-	retryableFn := client.buildRetryableFn(ctx, server.URL, req, "chat")
-	result, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL, req, "chat")
+	result, _, _, _, err := retryableFn(ctx, 0)
 
 	// Should succeed at HTTP level
 	if err != nil {
@@ -229,7 +238,7 @@ func TestIsRetryableError_ServerError(t *testing.T) {
 		{"502 Bad Gateway", 502, true},
 		{"503 Service Unavailable", 503, true},
 		{"429 Rate Limit", 429, true},
-		{"400 Bad Request", 400, true},
+		{"400 Bad Request", 400, false},
 		{"401 Unauthorized", 401, false},
 		{"403 Forbidden", 403, false},
 		{"404 Not Found", 404, false},
@@ -308,8 +317,8 @@ func TestBuildRetryableFn_InvalidRequestBody(t *testing.T) {
 	// Create an invalid request body that can't be marshaled
 	invalidBody := make(chan int) // channels can't be marshaled to JSON
 
-	retryableFn := client.buildRetryableFn(ctx, "http://example.com", invalidBody, "test")
-	_, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn("http://example.com", invalidBody, "test")
+	_, _, _, _, err := retryableFn(ctx, 0)
 
 	if err == nil {
 		t.Error("Expected error when marshaling invalid request body")
@@ -331,8 +340,8 @@ func TestBuildRetryableFn_ContextCancellation(t *testing.T) {
 		Messages: []ChatMessage{{Role: MessageRoleUser, Content: &userPrompt}},
 	}
 
-	retryableFn := client.buildRetryableFn(ctx, "http://example.com", req, "test")
-	_, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn("http://example.com", req, "test")
+	_, _, _, _, err := retryableFn(ctx, 0)
 
 	if err == nil {
 		t.Error("Expected error due to canceled context")
@@ -373,8 +382,8 @@ func TestBuildRetryableFn_WithDumpRequests(t *testing.T) {
 		Messages: []ChatMessage{{Role: MessageRoleUser, Content: &userPrompt}},
 	}
 
-	retryableFn := client.buildRetryableFn(ctx, server.URL, req, "chat")
-	_, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL, req, "chat")
+	_, _, _, _, err := retryableFn(ctx, 0)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -436,8 +445,8 @@ func TestChatCompletion_EmptyChoices(t *testing.T) {
 		Messages: []ChatMessage{{Role: MessageRoleUser, Content: &userPrompt}},
 	}
 
-	retryableFn := client.buildRetryableFn(ctx, server.URL, req, "chat")
-	result, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL, req, "chat")
+	result, _, _, _, err := retryableFn(ctx, 0)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -518,8 +527,8 @@ func TestChatCompletionRequest_AllFields(t *testing.T) {
 		},
 	}
 
-	retryableFn := client.buildRetryableFn(ctx, server.URL, req, "chat")
-	_, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL, req, "chat")
+	_, _, _, _, err := retryableFn(ctx, 0)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
@@ -648,8 +657,8 @@ func TestSaveResponseToFile_Coverage(t *testing.T) {
 		Messages: []ChatMessage{{Role: MessageRoleUser, Content: &userPrompt}},
 	}
 
-	retryableFn := client.buildRetryableFn(ctx, server.URL, req, "chat")
-	_, _, _, err := retryableFn(0)
+	retryableFn := client.buildRetryableFn(server.URL, req, "chat")
+	_, _, _, _, err := retryableFn(ctx, 0)
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
@@ -657,3 +666,461 @@ func TestSaveResponseToFile_Coverage(t *testing.T) {
 	// The file should have been created in debug_llm_requests/test-model/
 	// We won't verify the file contents in this test
 }
+
+// retryCountingRecorder counts retries reported by the client
+type retryCountingRecorder struct {
+	types.NopMetricsRecorder
+	retries map[string]int
+}
+
+func (r *retryCountingRecorder) RecordRetry(api string) {
+	r.retries[api]++
+}
+
+func TestCreateAndRunRetryableRequest_RecordsRetries(t *testing.T) {
+	attempt := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt++
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	recorder := &retryCountingRecorder{retries: make(map[string]int)}
+	client := &OpenAIClient{
+		APIKey:      "test-key",
+		HTTPClient:  server.Client(),
+		RetryConfig: retry.Config{MaxRetries: 3, BaseDelay: 1},
+		Metrics:     recorder,
+	}
+
+	_, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if recorder.retries["OpenAI chat"] != 2 {
+		t.Errorf("Expected 2 retries recorded, got %v", recorder.retries)
+	}
+}
+
+func TestCreateAndRunRetryableRequest_Tracing(t *testing.T) {
+	attempt := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt++
+		if attempt < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	client := &OpenAIClient{
+		APIKey:      "test-key",
+		HTTPClient:  server.Client(),
+		RetryConfig: retry.Config{MaxRetries: 2, BaseDelay: 1},
+		Tracer:      tp.Tracer("test"),
+	}
+
+	_, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	attempts, requests := 0, 0
+	parents := make(map[string]bool)
+	for _, span := range exporter.GetSpans() {
+		switch span.Name {
+		case "retry.attempt":
+			attempts++
+			parents[span.SpanContext.SpanID().String()] = true
+		case "openai.http":
+			requests++
+		}
+	}
+
+	if attempts != 2 || requests != 2 {
+		t.Fatalf("Expected 2 attempt and 2 HTTP spans, got %d and %d", attempts, requests)
+	}
+
+	// HTTP spans are children of their attempt span
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "openai.http" && !parents[span.Parent.SpanID().String()] {
+			t.Error("Expected HTTP span to be a child of a retry attempt span")
+		}
+	}
+}
+
+func TestCreateAndRunRetryableRequest_Logging(t *testing.T) {
+	attempt := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt++
+		if attempt < 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := &OpenAIClient{
+		APIKey:      "test-key",
+		HTTPClient:  server.Client(),
+		RetryConfig: retry.Config{MaxRetries: 2, BaseDelay: 1},
+		Logger:      slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+
+	_, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var record map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		if r["msg"] == "retryable API error" {
+			record = r
+		}
+	}
+
+	if record == nil {
+		t.Fatalf("Expected retryable error to be logged, got:\n%s", buf.String())
+	}
+	if record["api"] != "OpenAI chat" || record["attempt"] != float64(1) || record["status_code"] != float64(http.StatusTooManyRequests) {
+		t.Errorf("Unexpected log fields: %v", record)
+	}
+}
+
+func TestCreateAndRunRetryableRequest_RetryExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &OpenAIClient{
+		APIKey:      "test-key",
+		HTTPClient:  server.Client(),
+		RetryConfig: retry.Config{MaxRetries: 2, BaseDelay: 1},
+	}
+
+	_, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+
+	var exhaustedErr *retry.RetryExhaustedError
+	if !errors.As(err, &exhaustedErr) {
+		t.Fatalf("Expected RetryExhaustedError, got %v", err)
+	}
+	if exhaustedErr.MaxAttempts != 3 || exhaustedErr.LastStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected RetryExhaustedError: %+v", exhaustedErr)
+	}
+
+	var chatErr *ChatCompletionError
+	if !errors.As(err, &chatErr) || chatErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected last ChatCompletionError to be preserved, got %v", err)
+	}
+}
+
+func TestCreateAndRunRetryableRequest_CircuitBreaker(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Bad-Request") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &OpenAIClient{
+		APIKey:     "test-key",
+		HTTPClient: server.Client(),
+		Breaker: retry.NewBreaker("OpenAI", retry.BreakerConfig{
+			Window:      time.Minute,
+			MinRequests: 2,
+			FailureRate: 0.5,
+			CoolDown:    time.Minute,
+		}),
+	}
+
+	for range 2 {
+		if _, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat"); err == nil {
+			t.Fatal("Expected error from unavailable server")
+		}
+	}
+
+	_, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("Expected circuit open error, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected open breaker to skip the HTTP request, got %d requests", requests)
+	}
+}
+
+func TestCreateAndRunRetryableRequest_CircuitBreakerReleasesAbandonedProbe(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Slow") != "" {
+			<-unblock
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	breaker := retry.NewBreaker("OpenAI", retry.BreakerConfig{Window: time.Minute, MinRequests: 1, FailureRate: 0.5})
+	client := &OpenAIClient{APIKey: "test-key", HTTPClient: server.Client(), Breaker: breaker}
+
+	client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if breaker.State() != retry.BreakerHalfOpen {
+		t.Fatalf("Expected the breaker to trip straight to half-open, got %s", breaker.State())
+	}
+
+	// The probe outlives the caller's deadline, which says nothing about whether OpenAI recovered
+	client.HTTPClient = &http.Client{Transport: headerTransport{"X-Slow", server.Client().Transport}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.createAndRunRetryableRequest(ctx, server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the caller's deadline, got %v", err)
+	}
+	if breaker.State() != retry.BreakerHalfOpen {
+		t.Errorf("Expected an abandoned probe to leave the breaker half-open, got %s", breaker.State())
+	}
+
+	// A request that can't be encoded never reaches OpenAI, so it doesn't count as a failure
+	client.Breaker = retry.NewBreaker("OpenAI", retry.BreakerConfig{Window: time.Minute, MinRequests: 1, FailureRate: 0.5, CoolDown: time.Minute})
+	if _, err := client.createAndRunRetryableRequest(context.Background(), server.URL, map[string]any{"bad": make(chan int)}, "chat"); err == nil {
+		t.Fatal("Expected an encoding error")
+	}
+	if client.Breaker.State() != retry.BreakerClosed {
+		t.Errorf("Expected an encoding error not to trip the breaker, got %s", client.Breaker.State())
+	}
+}
+
+// headerTransport sets a header on every request before sending it
+type headerTransport struct {
+	name string
+	next http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set(t.name, "1")
+	return t.next.RoundTrip(r)
+}
+
+func TestLastStatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"network error", errors.New("connection refused"), 0},
+		{"rate limited", &ChatCompletionError{StatusCode: 429}, 429},
+		{"retries exhausted", &retry.RetryExhaustedError{LastStatusCode: 502}, 502},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastStatusCode(tt.err); got != tt.want {
+				t.Errorf("lastStatusCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       ErrorCode
+	}{
+		{"rate limit", 429, `{"error": {"code": "rate_limit_exceeded", "type": "requests"}}`, ErrorCodeRateLimit},
+		{"quota exceeded", 429, `{"error": {"code": "insufficient_quota", "type": "insufficient_quota"}}`, ErrorCodeQuotaExceeded},
+		{"context length", 400, `{"error": {"code": "context_length_exceeded", "type": "invalid_request_error"}}`, ErrorCodeContextLength},
+		{"context length message", 400, `{"error": {"message": "This model's maximum context length is 8192 tokens"}}`, ErrorCodeContextLength},
+		{"invalid model", 404, `{"error": {"code": "model_not_found", "type": "invalid_request_error"}}`, ErrorCodeInvalidRequest},
+		{"content filter", 400, `{"error": {"code": "content_filter", "type": "invalid_request_error"}}`, ErrorCodeContentFilter},
+		{"server error", 500, `{"error": {"type": "server_error"}}`, ErrorCodeServer},
+		{"failed generation", 400, `{"error": {"failed_generation": "partial"}}`, ErrorCodeServer},
+		{"unparsable bad request", 400, `bad gateway`, ErrorCodeInvalidRequest},
+		{"ok", 200, ``, ErrorCodeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.statusCode, []byte(tt.body)); got != tt.want {
+				t.Errorf("ClassifyError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAndRunRetryableRequest_RetryPolicy(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"code": "context_length_exceeded", "type": "invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	client := &OpenAIClient{
+		APIKey:      "test-key",
+		HTTPClient:  server.Client(),
+		RetryConfig: retry.Config{MaxRetries: 3, BaseDelay: 1},
+	}
+
+	_, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if requests != 1 {
+		t.Errorf("Expected context length errors not to be retried, got %d requests", requests)
+	}
+
+	var chatErr *ChatCompletionError
+	if !errors.As(err, &chatErr) || chatErr.Code != ErrorCodeContextLength || chatErr.Retryable {
+		t.Fatalf("Expected non-retryable context length error, got %v", err)
+	}
+	if !errors.Is(err, types.ErrContextLength) {
+		t.Error("Expected error to match types.ErrContextLength")
+	}
+
+	// A custom policy can retry other codes
+	requests = 0
+	client.RetryPolicy = map[ErrorCode]bool{ErrorCodeContextLength: true}
+	client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if requests != 4 {
+		t.Errorf("Expected custom policy to retry, got %d requests", requests)
+	}
+}
+
+// hedgeCountingRecorder counts hedged requests reported by the client
+type hedgeCountingRecorder struct {
+	types.NopMetricsRecorder
+	hedges atomic.Int32
+}
+
+func (r *hedgeCountingRecorder) RecordHedge(api string, won bool) {
+	r.hedges.Add(1)
+}
+
+func TestCreateAndRunRetryableRequest_Hedging(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	recorder := &hedgeCountingRecorder{}
+	client := &OpenAIClient{
+		APIKey:     "test-key",
+		HTTPClient: server.Client(),
+		Hedger:     retry.NewHedger(retry.HedgeConfig{MaxDelay: 20 * time.Millisecond}),
+		Metrics:    recorder,
+	}
+
+	// Requests that aren't idempotent are never hedged
+	requests.Store(1)
+	if _, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if recorder.hedges.Load() != 0 {
+		t.Fatalf("Expected no hedges, got %d", recorder.hedges.Load())
+	}
+
+	requests.Store(0)
+	start := time.Now()
+	if _, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4", Idempotent: true}, "chat"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the hedge to return before the slow request, took %v", elapsed)
+	}
+	if requests.Load() != 2 || recorder.hedges.Load() != 1 {
+		t.Errorf("Expected 2 requests and 1 hedge, got %d requests and %d hedges", requests.Load(), recorder.hedges.Load())
+	}
+}
+
+// rateLimitRecorder records rate limit waits reported by the client
+type rateLimitRecorder struct {
+	types.NopMetricsRecorder
+	waits atomic.Int32
+}
+
+func (r *rateLimitRecorder) RecordRateLimitWait(api string, wait time.Duration) {
+	r.waits.Add(1)
+}
+
+func TestCreateAndRunRetryableRequest_RateLimited(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	recorder := &rateLimitRecorder{}
+	client := &OpenAIClient{
+		APIKey:     "test-key",
+		HTTPClient: server.Client(),
+		Limiter:    ratelimit.New(ratelimit.Config{RequestsPerSecond: 1}),
+		Metrics:    recorder,
+	}
+
+	if _, err := client.createAndRunRetryableRequest(context.Background(), server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The second request must wait a second for the limiter, longer than the caller allows
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.createAndRunRetryableRequest(ctx, server.URL, ChatCompletionRequest{Model: "gpt-4"}, "chat")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded while waiting for the limiter, got %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected the limited request not to be sent, got %d requests", requests.Load())
+	}
+	if recorder.waits.Load() != 2 {
+		t.Errorf("Expected 2 rate limit waits recorded, got %d", recorder.waits.Load())
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	system := "0123456789abcdef"
+	user := "01234567"
+	req := ChatCompletionRequest{
+		Messages: []ChatMessage{
+			{Role: MessageRoleSystem, Content: &system},
+			{Role: MessageRoleUser, Content: &user},
+			{Role: MessageRoleUser},
+		},
+		MaxCompletionTokens: 50,
+	}
+
+	if got := estimateTokens(req); got != 56 {
+		t.Errorf("Expected 56 tokens, got %d", got)
+	}
+	if got := estimateTokens(map[string]any{}); got != 0 {
+		t.Errorf("Expected 0 tokens for other requests, got %d", got)
+	}
+}
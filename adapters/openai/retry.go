@@ -4,39 +4,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/FrenchMajesty/consistent-classifier/internal/ratelimit"
 	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // isRetryableError determines if an error should trigger a retry
 func (c *OpenAIClient) isRetryableError(err error, statusCode int, responseBody []byte) bool {
 	// Retry on network errors
-	if err != nil {
-		return true
-	}
-
-	// Retry on server errors (5xx)
-	if statusCode >= 500 {
-		return true
-	}
-
-	// Retry on rate limiting (429)
-	if statusCode == 429 {
-		return true
+	if statusCode == 0 {
+		return err != nil
 	}
 
-	// OpenAI sometimes returns 400 for transient issues
-	if statusCode == 400 {
-		return true
+	// Error responses are retried according to the policy for their error code
+	if statusCode != http.StatusOK {
+		return c.isRetryableCode(ClassifyError(statusCode, responseBody))
 	}
 
 	// Check for failed_generation in response body even with 200 OK
@@ -58,21 +52,67 @@ func (c *OpenAIClient) isRetryableError(err error, statusCode int, responseBody
 	return false
 }
 
+// isRetryableCode reports whether the retry policy retries errors with the given code
+func (c *OpenAIClient) isRetryableCode(code ErrorCode) bool {
+	policy := c.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	return policy[code]
+}
+
+// lastStatusCode returns the HTTP status code of the last response behind err, or 0 if there was none
+func lastStatusCode(err error) int {
+	var chatErr *ChatCompletionError
+	if errors.As(err, &chatErr) {
+		return chatErr.StatusCode
+	}
+
+	var exhaustedErr *retry.RetryExhaustedError
+	if errors.As(err, &exhaustedErr) {
+		return exhaustedErr.LastStatusCode
+	}
+
+	return 0
+}
+
 // createAndRunRetryableRequest executes an HTTP request with retry logic
 func (c *OpenAIClient) createAndRunRetryableRequest(ctx context.Context, url string, requestBody any, apiName string) ([]byte, error) {
 	// Setup retry options
 	opts := retry.Options{
 		Config:       c.RetryConfig,
 		ErrorChecker: c.isRetryableError,
-		Logger:       log.Printf,
+		Logger:       c.Logger,
+		Tracer:       c.Tracer,
+		Budget:       c.RetryBudget,
 		APIName:      "OpenAI " + apiName,
 	}
+	if chatReq, ok := requestBody.(ChatCompletionRequest); ok && chatReq.Idempotent {
+		opts.Hedger = c.Hedger
+	}
+	if c.Metrics != nil {
+		opts.OnRetry = func(apiName string, attempt int) {
+			c.Metrics.RecordRetry(apiName)
+		}
+		opts.OnHedge = c.Metrics.RecordHedge
+	}
+
+	// Fail fast while the provider is unhealthy
+	if err := c.Breaker.Allow(); err != nil {
+		return nil, err
+	}
 
 	// Define the retryable function
-	retryableFn := c.buildRetryableFn(ctx, url, requestBody, apiName)
+	retryableFn := c.buildRetryableFn(url, requestBody, apiName)
 
 	// Execute with retry logic
 	result, err := retry.Execute(ctx, opts, retryableFn)
+	if retry.CallerDone(ctx, err) {
+		// A request the caller gave up on says nothing about OpenAI, so it must not settle a probe
+		c.Breaker.Release()
+	} else {
+		c.Breaker.Record(retry.IsProviderFailure(ctx, err, lastStatusCode(err)))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -81,55 +121,100 @@ func (c *OpenAIClient) createAndRunRetryableRequest(ctx context.Context, url str
 }
 
 // buildRetryableFn builds a retryable function for the given request body
-func (c *OpenAIClient) buildRetryableFn(ctx context.Context, url string, requestBody any, apiName string) retry.RetryableFunc {
-	retryableFn := func(attempt int) (any, int, []byte, error) {
+func (c *OpenAIClient) buildRetryableFn(url string, requestBody any, apiName string) retry.RetryableFunc {
+	tokens := estimateTokens(requestBody)
+	retryableFn := func(ctx context.Context, attempt int) (_ any, statusCode int, _ http.Header, _ []byte, finalErr error) {
+		// Wait for the rate limiter before sending each attempt, including retries and hedges
+		release, waited, err := c.Limiter.Wait(ctx, tokens)
+		if c.Limiter != nil && c.Metrics != nil {
+			c.Metrics.RecordRateLimitWait("OpenAI "+apiName, waited)
+		}
+		if err != nil {
+			return nil, 0, nil, nil, err
+		}
+		defer release()
+
+		ctx, span := tracing.Start(ctx, c.Tracer, "openai.http",
+			attribute.String("http.request.method", http.MethodPost),
+			attribute.String("url.full", url),
+		)
+		defer func() {
+			if statusCode != 0 {
+				span.SetAttributes(tracing.AttrStatusCode.Int(statusCode))
+			}
+			tracing.End(span, finalErr)
+		}()
+		if chatReq, ok := requestBody.(ChatCompletionRequest); ok {
+			span.SetAttributes(tracing.AttrModel.String(chatReq.Model))
+		}
+
 		body, err := json.Marshal(requestBody)
 		if err != nil {
-			return nil, 0, nil, fmt.Errorf("failed to marshal %s request: %w", apiName, err)
+			return nil, 0, nil, nil, fmt.Errorf("failed to marshal %s request: %w", apiName, err)
 		}
 
 		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 		if err != nil {
-			return nil, 0, nil, fmt.Errorf("failed to create HTTP request: %w", err)
+			return nil, 0, nil, nil, fmt.Errorf("failed to create HTTP request: %w", err)
 		}
 		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
 		httpReq.Header.Set("Content-Type", "application/json")
 
 		resp, err := c.HTTPClient.Do(httpReq)
 		if err != nil {
-			return nil, 0, nil, err
+			return nil, 0, nil, nil, err
 		}
 		defer resp.Body.Close()
 
 		// Read the response body once
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, resp.StatusCode, nil, fmt.Errorf("failed to read %s response body: %w", apiName, err)
+			return nil, resp.StatusCode, resp.Header, nil, fmt.Errorf("failed to read %s response body: %w", apiName, err)
 		}
 
 		// Dump the request/response if enabled for debugging purposes
 		chatReq, ok := requestBody.(ChatCompletionRequest)
 		if c.DumpRequests && ok {
-			saveResponseToFile(chatReq.Model, chatReq, bodyBytes, resp.StatusCode)
+			c.saveResponseToFile(ctx, chatReq.Model, chatReq, bodyBytes, resp.StatusCode)
 		}
 
 		// If we get here and status is not OK, it's an error
 		if resp.StatusCode != http.StatusOK {
-			return nil, resp.StatusCode, bodyBytes, &ChatCompletionError{
+			code := ClassifyError(resp.StatusCode, bodyBytes)
+			return nil, resp.StatusCode, resp.Header, bodyBytes, &ChatCompletionError{
 				Message:    fmt.Sprintf("openai %s API error %d", apiName, resp.StatusCode),
 				StatusCode: resp.StatusCode,
+				Code:       code,
+				Retryable:  c.isRetryableCode(code),
 				RawBody:    json.RawMessage(bodyBytes),
 			}
 		}
 
-		return bodyBytes, resp.StatusCode, bodyBytes, nil
+		return bodyBytes, resp.StatusCode, resp.Header, bodyBytes, nil
 	}
 
 	return retryableFn
 }
 
+// estimateTokens estimates the tokens a request takes from the rate limiter: its messages plus
+// the completion tokens it may generate, as OpenAI counts them against the tokens-per-minute limit
+func estimateTokens(requestBody any) int {
+	chatReq, ok := requestBody.(ChatCompletionRequest)
+	if !ok {
+		return 0
+	}
+
+	tokens := chatReq.MaxCompletionTokens
+	for _, message := range chatReq.Messages {
+		if message.Content != nil {
+			tokens += ratelimit.EstimateTokens(*message.Content)
+		}
+	}
+	return tokens
+}
+
 // saveResponseToFile saves the request/response to a file for debugging purposes
-func saveResponseToFile(model string, req ChatCompletionRequest, bodyBytes []byte, statusCode int) {
+func (c *OpenAIClient) saveResponseToFile(ctx context.Context, model string, req ChatCompletionRequest, bodyBytes []byte, statusCode int) {
 	// Create a unique filename with timestamp
 	timestamp := time.Now().Format("20060102_150405")
 	random := uuid.New().String()[:8]
@@ -138,14 +223,14 @@ func saveResponseToFile(model string, req ChatCompletionRequest, bodyBytes []byt
 	// Create model-specific directory
 	modelDir := fmt.Sprintf("debug_llm_requests/%s", model)
 	if err := os.MkdirAll(modelDir, 0755); err != nil {
-		log.Printf("Error creating directory %s: %v", modelDir, err)
+		c.logError(ctx, "failed to create request dump directory", slog.String("path", modelDir), slog.Any("error", err))
 		return
 	}
 
 	// Parse response body as JSON
 	var responseBody any
 	if err := json.Unmarshal(bodyBytes, &responseBody); err != nil {
-		log.Printf("Error parsing response body as JSON: %v", err)
+		c.logError(ctx, "failed to parse response body for request dump", slog.Int("status_code", statusCode), slog.Any("error", err))
 		return
 	}
 
@@ -159,7 +244,7 @@ func saveResponseToFile(model string, req ChatCompletionRequest, bodyBytes []byt
 	// Marshal to JSON
 	jsonData, err := json.MarshalIndent(responseData, "", "  ")
 	if err != nil {
-		log.Printf("Error marshaling response data: %v", err)
+		c.logError(ctx, "failed to marshal request dump", slog.Any("error", err))
 		return
 	}
 
@@ -167,7 +252,14 @@ func saveResponseToFile(model string, req ChatCompletionRequest, bodyBytes []byt
 	filepath := filepath.Join(modelDir, filename)
 	err = os.WriteFile(filepath, jsonData, 0644)
 	if err != nil {
-		log.Printf("Error writing to file %s: %v", filepath, err)
+		c.logError(ctx, "failed to write request dump", slog.String("path", filepath), slog.Any("error", err))
 		return
 	}
 }
+
+// logError logs an error if a logger is configured
+func (c *OpenAIClient) logError(ctx context.Context, message string, attrs ...slog.Attr) {
+	if c.Logger != nil {
+		c.Logger.LogAttrs(ctx, slog.LevelError, message, attrs...)
+	}
+}
@@ -56,6 +56,16 @@ func (idx *indexOperations) FindById(ctx context.Context, id string) (*pinecone.
 	return vector.Matches[0].Vector, nil
 }
 
+// Fetch returns the vectors with the given IDs, keyed by ID. Missing IDs are absent from the result.
+func (idx *indexOperations) Fetch(ctx context.Context, ids []string) (map[string]*Vector, error) {
+	response, err := idx.index.FetchVectors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Vectors, nil
+}
+
 // Search performs a vector similarity search in the index
 func (idx *indexOperations) Search(ctx context.Context, queryVector []float32, topK int, filter map[string]any, includeMetadata bool) (_ []QueryMatch, finalErr error) {
 	// Convert filter to Pinecone's MetadataFilter format
@@ -106,6 +116,16 @@ func (idx *indexOperations) UpdateMetadata(ctx context.Context, vectorID string,
 	})
 }
 
+// Dimension returns the dimension of the vectors stored in the index
+func (idx *indexOperations) Dimension(ctx context.Context) (int, error) {
+	stats, err := idx.index.DescribeIndexStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(stats.Dimension), nil
+}
+
 // Delete removes vectors from the index
 func (idx *indexOperations) Delete(ctx context.Context, ids []string) error {
 	return idx.index.DeleteVectorsById(ctx, ids)
@@ -0,0 +1,14 @@
+package adapters
+
+import "github.com/FrenchMajesty/consistent-classifier/internal/ratelimit"
+
+// RateLimiter throttles requests to a provider by request rate, estimated token rate and concurrency
+type RateLimiter = ratelimit.Limiter
+
+// RateLimitConfig configures a RateLimiter. Zero values disable a limit.
+type RateLimitConfig = ratelimit.Config
+
+// NewRateLimiter creates a rate limiter. Share one between all clients using the same API key.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	return ratelimit.New(config)
+}
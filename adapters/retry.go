@@ -0,0 +1,167 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier/adapters/voyage"
+	"github.com/FrenchMajesty/consistent-classifier/internal/ratelimit"
+	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryBudget is a token bucket that limits retries across all requests sharing it
+type RetryBudget = retry.Budget
+
+// RetryJitter controls how retry delays are randomized
+type RetryJitter = retry.JitterMode
+
+const (
+	RetryJitterNone         = retry.JitterNone
+	RetryJitterFull         = retry.JitterFull
+	RetryJitterDecorrelated = retry.JitterDecorrelated
+)
+
+// CircuitBreaker fails provider calls fast while the provider is unhealthy
+type CircuitBreaker = retry.Breaker
+
+// CircuitBreakerConfig configures a CircuitBreaker's failure-rate window and cool-down
+type CircuitBreakerConfig = retry.BreakerConfig
+
+// NewCircuitBreaker creates a closed circuit breaker. name identifies the provider in errors.
+func NewCircuitBreaker(name string, config CircuitBreakerConfig) *CircuitBreaker {
+	return retry.NewBreaker(name, config)
+}
+
+// DefaultCircuitBreakerConfig returns the configuration used by the default adapters
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return retry.DefaultBreakerConfig()
+}
+
+// Hedger sends a second attempt when a call is slower than a percentile of recent latencies
+type Hedger = retry.Hedger
+
+// HedgeConfig configures a Hedger's latency percentile and delay bounds
+type HedgeConfig = retry.HedgeConfig
+
+// NewHedger creates a hedger. Share one only between calls with similar latencies.
+func NewHedger(config HedgeConfig) *Hedger {
+	return retry.NewHedger(config)
+}
+
+// DefaultHedgeConfig hedges calls slower than the p95 of recent calls
+func DefaultHedgeConfig() HedgeConfig {
+	return retry.DefaultHedgeConfig()
+}
+
+// NewRetryBudget creates a budget of up to capacity retries, refilling refillPerSecond retries per second
+func NewRetryBudget(capacity int, refillPerSecond float64) *RetryBudget {
+	return retry.NewBudget(capacity, refillPerSecond)
+}
+
+// RetryConfig configures retry attempts and backoff for provider calls
+type RetryConfig = retry.Config
+
+// DefaultRetryConfig returns the retry configuration used by the default adapters
+func DefaultRetryConfig() RetryConfig {
+	return retry.DefaultConfig()
+}
+
+// callOptions configures how an adapter calls its provider
+type callOptions struct {
+	retry   retry.Config
+	timeout time.Duration // Per-attempt timeout. If 0, attempts are bounded only by the caller's context.
+	breaker *retry.Breaker
+	hedger  *retry.Hedger // Optional. Only set for idempotent calls.
+	limiter *ratelimit.Limiter
+	tokens  int // Estimated tokens each attempt takes from the limiter
+	metrics types.MetricsRecorder
+	tracer  trace.Tracer
+	logger  *slog.Logger
+}
+
+// callProvider runs fn with retries, a per-attempt timeout and the circuit breaker, and wraps
+// failures in a ProviderError
+func callProvider[T any](ctx context.Context, opts callOptions, provider string, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := opts.breaker.Allow(); err != nil {
+		return zero, newProviderError(provider, op, err)
+	}
+
+	retryOpts := retry.Options{
+		Config: opts.retry,
+		ErrorChecker: func(err error, statusCode int, responseBody []byte) bool {
+			if err == nil {
+				return false
+			}
+			// An attempt that timed out is retried as long as the caller is still waiting
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return true
+			}
+			return isRetryable(err, statusCode)
+		},
+		Logger:  opts.logger,
+		Tracer:  opts.tracer,
+		Hedger:  opts.hedger,
+		APIName: provider + " " + op,
+	}
+	if opts.metrics != nil {
+		retryOpts.OnRetry = func(apiName string, attempt int) {
+			opts.metrics.RecordRetry(apiName)
+		}
+		retryOpts.OnHedge = opts.metrics.RecordHedge
+	}
+
+	result, err := retry.Execute(ctx, retryOpts, func(ctx context.Context, attempt int) (any, int, http.Header, []byte, error) {
+		// Time spent waiting for the limiter doesn't count towards the attempt timeout
+		release, waited, err := opts.limiter.Wait(ctx, opts.tokens)
+		if opts.limiter != nil && opts.metrics != nil {
+			opts.metrics.RecordRateLimitWait(retryOpts.APIName, waited)
+		}
+		if err != nil {
+			return nil, 0, nil, nil, err
+		}
+		defer release()
+
+		if opts.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+			defer cancel()
+		}
+
+		result, err := fn(ctx)
+		if err == nil {
+			return result, http.StatusOK, nil, nil, nil
+		}
+
+		// Voyage errors carry the response headers, so Retry-After is honoured
+		var header http.Header
+		var apiErr *voyage.APIError
+		if errors.As(err, &apiErr) {
+			header = apiErr.Header
+		}
+		return nil, providerStatusCode(provider, err), header, nil, err
+	})
+
+	err = newProviderError(provider, op, err)
+	if retry.CallerDone(ctx, err) {
+		// A call the caller gave up on says nothing about the provider, so it must not settle a probe
+		opts.breaker.Release()
+	} else {
+		var providerErr *types.ProviderError
+		statusCode := 0
+		if errors.As(err, &providerErr) {
+			statusCode = providerErr.StatusCode
+		}
+		opts.breaker.Record(retry.IsProviderFailure(ctx, err, statusCode))
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	return result.(T), nil
+}
@@ -1,20 +1,22 @@
 package voyage
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"sync"
+	"io"
+	"net/http"
 
 	"github.com/austinfhunter/voyageai"
 )
 
-var client *voyageai.VoyageClient
-var once sync.Once
-
 const EMBEDDING_DIMENSIONS = 1024
 
 const VOYAGEAI_EMBEDDING_MODEL = "voyage-3.5-lite"
 
+const voyageBaseURL = "https://api.voyageai.com/v1"
+
 type VoyageEmbeddingType string
 
 const (
@@ -23,23 +25,37 @@ const (
 	VoyageEmbeddingTypeDefault  VoyageEmbeddingType = ""
 )
 
+// APIError is returned when the Voyage API responds with an error status
+type APIError struct {
+	StatusCode int
+	Header     http.Header
+	Detail     string
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("voyage API error %d: %s", e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("voyage API error %d", e.StatusCode)
+}
+
 // embeddingService handles generating embeddings for text
 type voyageService struct {
+	apiKey     string
 	dimensions int
 	model      string
+	baseURL    string
+	httpClient *http.Client
 }
 
 // NewEmbeddingService creates a new embedding service
 func NewEmbeddingService(apiKey string) *voyageService {
-	once.Do(func() {
-		client = voyageai.NewClient(&voyageai.VoyageClientOpts{
-			Key: apiKey,
-		})
-	})
-
 	instance := &voyageService{
+		apiKey:     apiKey,
 		dimensions: EMBEDDING_DIMENSIONS,
 		model:      VOYAGEAI_EMBEDDING_MODEL,
+		baseURL:    voyageBaseURL,
+		httpClient: http.DefaultClient,
 	}
 
 	return instance
@@ -55,46 +71,83 @@ func (es *voyageService) SetModel(model string) {
 	es.model = model
 }
 
-// GenerateEmbedding generates an embedding for a single text using VoyageAI
-func (es *voyageService) GenerateEmbedding(ctx context.Context, text string, embeddingType VoyageEmbeddingType) ([]float32, error) {
-	dimensions := es.GetEmbeddingDimensions()
-	inputType := parseEmbeddingType(embeddingType)
+// SetBaseURL sets the base URL of the Voyage API
+func (es *voyageService) SetBaseURL(baseURL string) {
+	es.baseURL = baseURL
+}
 
-	embeddings, err := client.Embed(
-		[]string{text},
-		es.model,
-		&voyageai.EmbeddingRequestOpts{
-			InputType:       inputType,
-			OutputDimension: &dimensions,
-		},
-	)
+// SetHTTPClient sets the HTTP client used for requests
+func (es *voyageService) SetHTTPClient(httpClient *http.Client) {
+	es.httpClient = httpClient
+}
 
+// GenerateEmbedding generates an embedding for a single text using VoyageAI
+func (es *voyageService) GenerateEmbedding(ctx context.Context, text string, embeddingType VoyageEmbeddingType) ([]float32, error) {
+	embeddings, err := es.embed(ctx, []string{text}, embeddingType)
 	if err != nil {
 		return nil, fmt.Errorf("could not get embedding: %w", err)
 	}
 
-	return embeddings.Data[0].Embedding, nil
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("could not get embedding: empty response")
+	}
+
+	return embeddings[0].Embedding, nil
 }
 
 // GenerateEmbeddings generates embeddings for multiple texts using VoyageAI
 func (es *voyageService) GenerateEmbeddings(ctx context.Context, texts []string, embeddingType VoyageEmbeddingType) ([]voyageai.EmbeddingObject, error) {
+	embeddings, err := es.embed(ctx, texts, embeddingType)
+	if err != nil {
+		return nil, fmt.Errorf("could not get embeddings: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+// embed sends a single embeddings request. Error responses are returned as *APIError.
+func (es *voyageService) embed(ctx context.Context, texts []string, embeddingType VoyageEmbeddingType) ([]voyageai.EmbeddingObject, error) {
 	dimensions := es.GetEmbeddingDimensions()
-	inputType := parseEmbeddingType(embeddingType)
+	body, err := json.Marshal(voyageai.EmbeddingRequest{
+		Input:           texts,
+		Model:           es.model,
+		InputType:       parseEmbeddingType(embeddingType),
+		OutputDimension: &dimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, es.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+es.apiKey)
+	req.Header.Set("Content-Type", "application/json")
 
-	embeddings, err := client.Embed(
-		texts,
-		es.model,
-		&voyageai.EmbeddingRequestOpts{
-			InputType:       inputType,
-			OutputDimension: &dimensions,
-		},
-	)
+	resp, err := es.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("could not get embeddings: %w", err)
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr voyageai.APIError
+		_ = json.Unmarshal(respBody, &apiErr)
+		return nil, &APIError{StatusCode: resp.StatusCode, Header: resp.Header, Detail: apiErr.Detail}
+	}
+
+	var embeddingResp voyageai.EmbeddingResponse
+	if err := json.Unmarshal(respBody, &embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
 	}
 
-	return embeddings.Data, nil
+	return embeddingResp.Data, nil
 }
 
 func parseEmbeddingType(embeddingType VoyageEmbeddingType) *string {
@@ -0,0 +1,194 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+// Default batching settings used when BatchingConfig fields are zero
+const (
+	DefaultBatchMaxWait = 5 * time.Millisecond
+	DefaultBatchMaxSize = 64
+)
+
+// BatchingConfig controls how a BatchingEmbeddingClient groups calls
+type BatchingConfig struct {
+	// MaxWait is how long the first call in a batch waits for others to join. If 0, uses DefaultBatchMaxWait.
+	MaxWait time.Duration
+
+	// MaxSize sends a batch as soon as it holds this many distinct texts. If 0, uses DefaultBatchMaxSize.
+	MaxSize int
+}
+
+// BatchingEmbeddingClient collects concurrent GenerateEmbedding calls and sends them as one
+// batched request. Identical texts that are in flight at the same time are embedded once.
+// If the wrapped client doesn't implement BatchEmbeddingClient, each distinct text is
+// embedded with its own call.
+type BatchingEmbeddingClient struct {
+	client EmbeddingClient
+	config BatchingConfig
+
+	lock    sync.Mutex
+	pending map[types.EmbeddingInputType]*embeddingBatch // Batches are sent per input type
+	calls   map[embeddingKey]*embeddingCall              // In-flight calls, across pending and sent batches
+}
+
+// embeddingKey identifies calls that can share a result
+type embeddingKey struct {
+	inputType types.EmbeddingInputType
+	text      string
+}
+
+// embeddingBatch is a group of distinct texts sent in one request
+type embeddingBatch struct {
+	ctx       context.Context
+	inputType types.EmbeddingInputType
+	texts     []string
+	calls     []*embeddingCall
+	timer     *time.Timer
+}
+
+// embeddingCall is the shared result of embedding one text
+type embeddingCall struct {
+	done      chan struct{}
+	embedding []float32
+	err       error
+}
+
+// NewBatchingEmbeddingClient wraps client so concurrent calls are batched and deduplicated. The
+// returned *BatchingEmbeddingClient implements InputTypeEmbeddingClient only if client does, so the
+// classifier doesn't embed texts again as documents for a client that ignores input types.
+func NewBatchingEmbeddingClient(client EmbeddingClient, config BatchingConfig) EmbeddingClient {
+	if config.MaxWait <= 0 {
+		config.MaxWait = DefaultBatchMaxWait
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultBatchMaxSize
+	}
+
+	batching := &BatchingEmbeddingClient{
+		client:  client,
+		config:  config,
+		pending: make(map[types.EmbeddingInputType]*embeddingBatch),
+		calls:   make(map[embeddingKey]*embeddingCall),
+	}
+	if _, ok := client.(InputTypeEmbeddingClient); ok {
+		return &inputTypeBatchingEmbeddingClient{batching}
+	}
+	return batching
+}
+
+// GenerateEmbedding implements EmbeddingClient. It waits for the batch containing text to be
+// sent, or for ctx to be done. A canceled caller doesn't cancel the batch for other callers.
+func (c *BatchingEmbeddingClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return c.generateEmbedding(ctx, text, types.EmbeddingInputDefault)
+}
+
+// inputTypeBatchingEmbeddingClient is a BatchingEmbeddingClient wrapping an InputTypeEmbeddingClient
+type inputTypeBatchingEmbeddingClient struct {
+	*BatchingEmbeddingClient
+}
+
+// GenerateEmbeddingForInput implements InputTypeEmbeddingClient. Texts are only batched with
+// texts of the same input type.
+func (c *inputTypeBatchingEmbeddingClient) GenerateEmbeddingForInput(ctx context.Context, text string, inputType types.EmbeddingInputType) ([]float32, error) {
+	return c.generateEmbedding(ctx, text, inputType)
+}
+
+// generateEmbedding adds text to the batch of its input type, or joins an identical call in flight
+func (c *BatchingEmbeddingClient) generateEmbedding(ctx context.Context, text string, inputType types.EmbeddingInputType) ([]float32, error) {
+	key := embeddingKey{inputType: inputType, text: text}
+
+	c.lock.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		call = &embeddingCall{done: make(chan struct{})}
+		c.calls[key] = call
+		c.enqueue(ctx, key, call)
+	}
+	c.lock.Unlock()
+
+	select {
+	case <-call.done:
+		return call.embedding, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds text to the pending batch, sending it once full. Caller must hold the lock.
+func (c *BatchingEmbeddingClient) enqueue(ctx context.Context, key embeddingKey, call *embeddingCall) {
+	batch := c.pending[key.inputType]
+	if batch == nil {
+		// The batch keeps the first caller's values (request ID, trace) but not its cancellation
+		batch = &embeddingBatch{ctx: context.WithoutCancel(ctx), inputType: key.inputType}
+		batch.timer = time.AfterFunc(c.config.MaxWait, func() { c.flush(batch) })
+		c.pending[key.inputType] = batch
+	}
+
+	batch.texts = append(batch.texts, key.text)
+	batch.calls = append(batch.calls, call)
+	if len(batch.texts) >= c.config.MaxSize {
+		batch.timer.Stop()
+		delete(c.pending, key.inputType)
+		go c.send(batch)
+	}
+}
+
+// flush sends batch if it is still pending
+func (c *BatchingEmbeddingClient) flush(batch *embeddingBatch) {
+	c.lock.Lock()
+	if c.pending[batch.inputType] != batch {
+		c.lock.Unlock()
+		return
+	}
+	delete(c.pending, batch.inputType)
+	c.lock.Unlock()
+
+	c.send(batch)
+}
+
+// send embeds the batch's texts and hands each caller its result
+func (c *BatchingEmbeddingClient) send(batch *embeddingBatch) {
+	c.embed(batch)
+
+	c.lock.Lock()
+	for i, text := range batch.texts {
+		delete(c.calls, embeddingKey{inputType: batch.inputType, text: text})
+		close(batch.calls[i].done)
+	}
+	c.lock.Unlock()
+}
+
+// embed sets each call's result, with one batched request if the client supports it,
+// otherwise with one call per text
+func (c *BatchingEmbeddingClient) embed(batch *embeddingBatch) {
+	if client, ok := c.client.(BatchEmbeddingClient); ok {
+		embeddings, err := client.GenerateEmbeddings(batch.ctx, batch.texts, batch.inputType)
+		if err == nil && len(embeddings) != len(batch.texts) {
+			err = fmt.Errorf("batch embedding returned %d embeddings for %d texts", len(embeddings), len(batch.texts))
+		}
+		for i, call := range batch.calls {
+			if err != nil {
+				call.err = err
+				continue
+			}
+			call.embedding = embeddings[i]
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i, call := range batch.calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			call.embedding, call.err = embed(batch.ctx, c.client, batch.texts[i], batch.inputType)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,219 @@
+package classifier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestBatchingEmbeddingClient_BatchesConcurrentCalls(t *testing.T) {
+	mock := &testutil.MockBatchEmbeddingClient{}
+	client := classifier.NewBatchingEmbeddingClient(mock, classifier.BatchingConfig{MaxWait: 20 * time.Millisecond, MaxSize: 100})
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text := fmt.Sprintf("text %02d", i)
+			embedding, err := client.GenerateEmbedding(context.Background(), text)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if embedding[0] != float32(len(text))/100.0 {
+				t.Errorf("Expected the embedding for %q, got %v", text, embedding)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if batches := mock.Batches(); len(batches) != 1 || batches[0] != 10 {
+		t.Errorf("Expected a single batch of 10 texts, got %v", batches)
+	}
+	if mock.CallCount != 0 {
+		t.Errorf("Expected no single-text calls, got %d", mock.CallCount)
+	}
+}
+
+func TestBatchingEmbeddingClient_MaxSize(t *testing.T) {
+	mock := &testutil.MockBatchEmbeddingClient{}
+	client := classifier.NewBatchingEmbeddingClient(mock, classifier.BatchingConfig{MaxWait: time.Hour, MaxSize: 2})
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GenerateEmbedding(context.Background(), fmt.Sprintf("text %d", i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if batches := mock.Batches(); len(batches) != 2 || batches[0] != 2 || batches[1] != 2 {
+		t.Errorf("Expected two full batches, got %v", batches)
+	}
+}
+
+func TestBatchingEmbeddingClient_DeduplicatesInFlightTexts(t *testing.T) {
+	release := make(chan struct{})
+	mock := &testutil.MockBatchEmbeddingClient{
+		GenerateEmbeddingsFunc: func(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error) {
+			<-release
+			embeddings := make([][]float32, len(texts))
+			for i := range texts {
+				embeddings[i] = []float32{1}
+			}
+			return embeddings, nil
+		},
+	}
+	client := classifier.NewBatchingEmbeddingClient(mock, classifier.BatchingConfig{MaxWait: time.Millisecond})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GenerateEmbedding(context.Background(), "same text"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// Let the first batch be sent, so later calls join it while it is in flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if batches := mock.Batches(); len(batches) != 1 || batches[0] != 1 {
+		t.Errorf("Expected one batch with the text once, got %v", batches)
+	}
+}
+
+func TestBatchingEmbeddingClient_Errors(t *testing.T) {
+	batchErr := errors.New("voyage down")
+	mock := &testutil.MockBatchEmbeddingClient{
+		GenerateEmbeddingsFunc: func(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error) {
+			return nil, batchErr
+		},
+	}
+	client := classifier.NewBatchingEmbeddingClient(mock, classifier.BatchingConfig{})
+
+	if _, err := client.GenerateEmbedding(context.Background(), "hello"); !errors.Is(err, batchErr) {
+		t.Errorf("Expected the batch error, got %v", err)
+	}
+
+	// Failed texts aren't cached, so a later call tries again
+	mock.GenerateEmbeddingsFunc = nil
+	if _, err := client.GenerateEmbedding(context.Background(), "hello"); err != nil {
+		t.Errorf("Expected retry to succeed, got %v", err)
+	}
+}
+
+func TestBatchingEmbeddingClient_CallerCanceled(t *testing.T) {
+	client := classifier.NewBatchingEmbeddingClient(&testutil.MockBatchEmbeddingClient{}, classifier.BatchingConfig{MaxWait: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GenerateEmbedding(ctx, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestBatchingEmbeddingClient_WithoutBatchSupport(t *testing.T) {
+	mock := &testutil.MockEmbeddingClient{}
+	client := classifier.NewBatchingEmbeddingClient(mock, classifier.BatchingConfig{MaxWait: 10 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	for _, text := range []string{"a", "b", "a"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GenerateEmbedding(context.Background(), text); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if mock.CallCount != 2 {
+		t.Errorf("Expected one call per distinct text, got %d", mock.CallCount)
+	}
+}
+
+func TestBatchingEmbeddingClient_BatchesByInputType(t *testing.T) {
+	var lock sync.Mutex
+	batches := map[types.EmbeddingInputType][]string{}
+	mock := &testutil.MockBatchEmbeddingClient{
+		GenerateEmbeddingsFunc: func(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error) {
+			lock.Lock()
+			batches[inputType] = append(batches[inputType], texts...)
+			lock.Unlock()
+			return make([][]float32, len(texts)), nil
+		},
+	}
+	client := classifier.NewBatchingEmbeddingClient(typedBatchEmbeddingClient{mock}, classifier.BatchingConfig{MaxWait: 10 * time.Millisecond})
+	typed, ok := client.(classifier.InputTypeEmbeddingClient)
+	if !ok {
+		t.Fatal("Expected a client with input types to stay an InputTypeEmbeddingClient")
+	}
+
+	var wg sync.WaitGroup
+	for _, inputType := range []types.EmbeddingInputType{types.EmbeddingInputQuery, types.EmbeddingInputDocument} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := typed.GenerateEmbeddingForInput(context.Background(), "same text", inputType); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(mock.Batches()) != 2 || len(batches[types.EmbeddingInputQuery]) != 1 || len(batches[types.EmbeddingInputDocument]) != 1 {
+		t.Errorf("Expected one batch per input type, got %v", batches)
+	}
+}
+
+func TestBatchingEmbeddingClient_WithoutInputTypes(t *testing.T) {
+	// Wrapping a client that ignores input types must not make it look like one that doesn't
+	for _, client := range []classifier.EmbeddingClient{&testutil.MockEmbeddingClient{}, &testutil.MockBatchEmbeddingClient{}} {
+		batching := classifier.NewBatchingEmbeddingClient(client, classifier.BatchingConfig{})
+		if _, ok := batching.(classifier.InputTypeEmbeddingClient); ok {
+			t.Errorf("Expected batching %T not to be an InputTypeEmbeddingClient", client)
+		}
+	}
+
+	// A cache miss then embeds the text once, reusing the lookup embedding to cache it
+	mock := &testutil.MockEmbeddingClient{}
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     classifier.NewBatchingEmbeddingClient(mock, classifier.BatchingConfig{MaxWait: time.Millisecond}),
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	if _, err := clf.Classify(context.Background(), "hello"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	clf.Close()
+
+	// One embedding for the text and one for its label
+	if mock.CallCount != 2 {
+		t.Errorf("Expected 2 embedding calls, got %d", mock.CallCount)
+	}
+}
@@ -0,0 +1,249 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+)
+
+// LabelUsage describes how often a label has been used and when it was first seen
+type LabelUsage = disjoint_set.LabelUsage
+
+// ClusterUsage describes a label cluster for choosing its canonical label
+type ClusterUsage struct {
+	// Root is the cluster's union-find root label
+	Root string
+
+	// Parent is the cluster's parent category, empty if none
+	Parent string
+
+	// Members are all labels in the cluster with their usage, sorted by label
+	Members []LabelUsage
+}
+
+// CanonicalLabelStrategy chooses the label Classify returns for a cluster. Without one, Classify
+// returns the cluster's union-find root, which is effectively arbitrary after a merge.
+// Canonical labels are chosen again whenever a cluster receives an LLM label, when a cache hit makes a
+// label more used than the current canonical label, and by RefreshCanonicalLabels.
+type CanonicalLabelStrategy interface {
+	// CanonicalLabel returns the cluster's canonical label. An empty label falls back to the root.
+	CanonicalLabel(ctx context.Context, cluster ClusterUsage) (string, error)
+}
+
+// MostFrequentLabel chooses the most used label. Ties go to the oldest, then alphabetically.
+type MostFrequentLabel struct{}
+
+// CanonicalLabel implements CanonicalLabelStrategy
+func (MostFrequentLabel) CanonicalLabel(ctx context.Context, cluster ClusterUsage) (string, error) {
+	return bestMember(cluster.Members, func(a, b LabelUsage) bool {
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.FirstSeen.Before(b.FirstSeen)
+	}), nil
+}
+
+// OldestLabel chooses the label seen first. Ties go alphabetically.
+type OldestLabel struct{}
+
+// CanonicalLabel implements CanonicalLabelStrategy
+func (OldestLabel) CanonicalLabel(ctx context.Context, cluster ClusterUsage) (string, error) {
+	return bestMember(cluster.Members, func(a, b LabelUsage) bool {
+		return a.FirstSeen.Before(b.FirstSeen)
+	}), nil
+}
+
+// ShortestLabel chooses the label with the fewest characters. Ties go to the most used, then alphabetically.
+type ShortestLabel struct{}
+
+// CanonicalLabel implements CanonicalLabelStrategy
+func (ShortestLabel) CanonicalLabel(ctx context.Context, cluster ClusterUsage) (string, error) {
+	return bestMember(cluster.Members, func(a, b LabelUsage) bool {
+		lengthA, lengthB := utf8.RuneCountInString(a.Label), utf8.RuneCountInString(b.Label)
+		if lengthA != lengthB {
+			return lengthA < lengthB
+		}
+		return a.Count > b.Count
+	}), nil
+}
+
+// TaxonomyPinnedLabel chooses a label listed in Taxonomy, so curated names always win. If several
+// members are listed, the most used one is chosen. Clusters with none are left to Fallback.
+type TaxonomyPinnedLabel struct {
+	// Taxonomy maps pinned labels to their parent category, as returned by LoadTaxonomyFile
+	Taxonomy map[string]string
+
+	// Fallback chooses the label of clusters with no pinned member. If nil, the root is used.
+	Fallback CanonicalLabelStrategy
+}
+
+// CanonicalLabel implements CanonicalLabelStrategy
+func (s TaxonomyPinnedLabel) CanonicalLabel(ctx context.Context, cluster ClusterUsage) (string, error) {
+	pinned := make([]LabelUsage, 0)
+	for _, member := range cluster.Members {
+		if _, ok := s.Taxonomy[member.Label]; ok {
+			pinned = append(pinned, member)
+		}
+	}
+	if len(pinned) > 0 {
+		return MostFrequentLabel{}.CanonicalLabel(ctx, ClusterUsage{Root: cluster.Root, Parent: cluster.Parent, Members: pinned})
+	}
+
+	if s.Fallback == nil {
+		return "", nil
+	}
+	return s.Fallback.CanonicalLabel(ctx, cluster)
+}
+
+// SummarizedLabel asks a ClusterNamer for a name summarising the cluster's labels, which need not be one of them.
+// The latest name of each cluster is cached with its membership, so the LLM is only called when a cluster changes.
+type SummarizedLabel struct {
+	namer ClusterNamer
+	lock  sync.Mutex
+	names map[string]clusterName // Latest name by cluster root
+}
+
+// clusterName is the name a ClusterNamer gave a cluster with the given members
+type clusterName struct {
+	members string // Sorted member labels
+	name    string
+}
+
+// NewSummarizedLabel creates a strategy that names clusters with namer
+func NewSummarizedLabel(namer ClusterNamer) *SummarizedLabel {
+	return &SummarizedLabel{namer: namer, names: make(map[string]clusterName)}
+}
+
+// CanonicalLabel implements CanonicalLabelStrategy
+func (s *SummarizedLabel) CanonicalLabel(ctx context.Context, cluster ClusterUsage) (string, error) {
+	// Singletons keep their only label
+	if len(cluster.Members) <= 1 {
+		return "", nil
+	}
+
+	labels := make([]string, len(cluster.Members))
+	for i, member := range cluster.Members {
+		labels[i] = member.Label
+	}
+	members := strings.Join(labels, "\x00")
+
+	s.lock.Lock()
+	cached, ok := s.names[cluster.Root]
+	s.lock.Unlock()
+	if ok && cached.members == members {
+		return cached.name, nil
+	}
+
+	name, err := s.namer.NameCluster(ctx, labels)
+	if err != nil {
+		return "", fmt.Errorf("failed to name cluster %s: %w", cluster.Root, err)
+	}
+	name = strings.TrimSpace(name)
+
+	// Replaces the name of the cluster's previous membership, so only one name per root is kept
+	s.lock.Lock()
+	s.names[cluster.Root] = clusterName{members: members, name: name}
+	s.lock.Unlock()
+
+	return name, nil
+}
+
+// bestMember returns the member ranked first by better, with ties going alphabetically
+func bestMember(members []LabelUsage, better func(a, b LabelUsage) bool) string {
+	if len(members) == 0 {
+		return ""
+	}
+
+	best := members[0]
+	for _, member := range members[1:] {
+		if better(member, best) || (!better(best, member) && member.Label < best.Label) {
+			best = member
+		}
+	}
+	return best.Label
+}
+
+// refreshCanonicalLabel chooses the canonical label of the label's cluster again. Does nothing without a strategy.
+func (c *Classifier) refreshCanonicalLabel(ctx context.Context, label string) error {
+	if c.canonicalStrategy == nil {
+		return nil
+	}
+
+	members := c.dsu.Members(label)
+	if members == nil {
+		return nil
+	}
+	root, _ := c.dsu.Root(label)
+
+	name, err := c.canonicalStrategy.CanonicalLabel(ctx, ClusterUsage{
+		Root:    root,
+		Parent:  c.dsu.Parent(label),
+		Members: members,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.dsu.SetCanonical(label, strings.TrimSpace(name))
+	return nil
+}
+
+// refreshCanonicalOnHit chooses the canonical label of the label's cluster again once a cache hit has
+// made the label more used than the current canonical label, so MostFrequentLabel follows usage.
+// Failures are logged, as the hit is still served with the current canonical label.
+func (c *Classifier) refreshCanonicalOnHit(ctx context.Context, label string) {
+	if c.canonicalStrategy == nil {
+		return
+	}
+
+	canonical := c.dsu.Canonical(label)
+	if canonical == label {
+		return
+	}
+	usage, _ := c.dsu.Usage(label)
+	current, ok := c.dsu.Usage(canonical)
+	if !ok || usage.Count <= current.Count {
+		return
+	}
+
+	if err := c.refreshCanonicalLabel(ctx, label); err != nil {
+		c.log().LogAttrs(ctx, slog.LevelWarn, "failed to refresh canonical label",
+			slog.String("label", label),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// RefreshCanonicalLabels chooses the canonical label of every cluster again, e.g. after changing
+// the strategy or to pick up usage imported with the DSU. Does nothing without a CanonicalLabelStrategy.
+func (c *Classifier) RefreshCanonicalLabels(ctx context.Context) error {
+	if c.canonicalStrategy == nil {
+		return nil
+	}
+
+	roots := c.dsu.Roots()
+	for _, root := range roots {
+		if err := c.refreshCanonicalLabel(ctx, root); err != nil {
+			return fmt.Errorf("failed to refresh canonical label of %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+// resultLabel returns the label Classify reports for a label's cluster: the canonical label when a
+// CanonicalLabelStrategy is set, otherwise the union-find root
+func (c *Classifier) resultLabel(label string) string {
+	if c.canonicalStrategy == nil {
+		root, ok := c.dsu.Root(label)
+		if !ok {
+			return label
+		}
+		return root
+	}
+	return c.dsu.Canonical(label)
+}
@@ -0,0 +1,186 @@
+package classifier_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestCanonicalLabelStrategies(t *testing.T) {
+	start := time.Now()
+	cluster := classifier.ClusterUsage{
+		Root: "tech_q",
+		Members: []classifier.LabelUsage{
+			{Label: "support", Count: 2, FirstSeen: start.Add(2 * time.Hour)},
+			{Label: "tech_q", Count: 1, FirstSeen: start},
+			{Label: "technical_question", Count: 5, FirstSeen: start.Add(time.Hour)},
+			{Label: "help", Count: 2, FirstSeen: start.Add(3 * time.Hour)},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		strategy classifier.CanonicalLabelStrategy
+		expected string
+	}{
+		{"most frequent", classifier.MostFrequentLabel{}, "technical_question"},
+		{"oldest", classifier.OldestLabel{}, "tech_q"},
+		{"shortest", classifier.ShortestLabel{}, "help"},
+		{"taxonomy pinned", classifier.TaxonomyPinnedLabel{Taxonomy: map[string]string{"support": "it", "help": "it"}}, "support"},
+		{"taxonomy fallback", classifier.TaxonomyPinnedLabel{Fallback: classifier.OldestLabel{}}, "tech_q"},
+		{"taxonomy without fallback", classifier.TaxonomyPinnedLabel{}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			label, err := tc.strategy.CanonicalLabel(context.Background(), cluster)
+			if err != nil {
+				t.Fatalf("CanonicalLabel failed: %v", err)
+			}
+			if label != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, label)
+			}
+		})
+	}
+}
+
+// countingNamer is a ClusterNamer that joins the labels it is given
+type countingNamer struct {
+	calls int
+}
+
+func (n *countingNamer) NameCluster(ctx context.Context, labels []string) (string, error) {
+	n.calls++
+	return strings.Join(labels, "+"), nil
+}
+
+func TestSummarizedLabel(t *testing.T) {
+	namer := &countingNamer{}
+	strategy := classifier.NewSummarizedLabel(namer)
+	cluster := classifier.ClusterUsage{
+		Root:    "refund",
+		Members: []classifier.LabelUsage{{Label: "money_back"}, {Label: "refund"}},
+	}
+
+	for range 2 {
+		label, err := strategy.CanonicalLabel(context.Background(), cluster)
+		if err != nil {
+			t.Fatalf("CanonicalLabel failed: %v", err)
+		}
+		if label != "money_back+refund" {
+			t.Errorf("Expected the namer's label, got %q", label)
+		}
+	}
+	if namer.calls != 1 {
+		t.Errorf("Expected the name to be cached, got %d calls", namer.calls)
+	}
+
+	// Only the latest membership of each cluster is kept, so returning to an old one names it again
+	grown := classifier.ClusterUsage{Root: "refund", Members: append(cluster.Members, classifier.LabelUsage{Label: "chargeback"})}
+	strategy.CanonicalLabel(context.Background(), grown)
+	strategy.CanonicalLabel(context.Background(), cluster)
+	if namer.calls != 3 {
+		t.Errorf("Expected superseded names to be evicted, got %d calls", namer.calls)
+	}
+
+	// Singletons keep their label without calling the namer
+	single := classifier.ClusterUsage{Root: "refund", Members: []classifier.LabelUsage{{Label: "refund"}}}
+	if label, _ := strategy.CanonicalLabel(context.Background(), single); label != "" || namer.calls != 3 {
+		t.Errorf("Expected singletons not to be named, got %q", label)
+	}
+}
+
+func TestClassifier_CanonicalLabelStrategy(t *testing.T) {
+	// Every label matches the tech_q cluster
+	mockVectorLabel := testutil.NewMockVectorClient()
+	mockVectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		return []types.VectorMatch{{ID: "tech_q", Score: 0.95, Metadata: map[string]any{"root": "tech_q"}}}, nil
+	}
+	labels := []string{"tech_q", "technical_question", "technical_question"}
+	calls := 0
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			calls++
+			return labels[calls-1], nil
+		},
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:        &testutil.MockEmbeddingClient{},
+		VectorClientContent:    testutil.NewMockVectorClient(),
+		VectorClientLabel:      mockVectorLabel,
+		LLMClient:              mockLLM,
+		DSUPersistence:         &testutil.MockDSUPersistence{},
+		CanonicalLabelStrategy: classifier.MostFrequentLabel{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	ctx := context.Background()
+	expected := []string{"tech_q", "tech_q", "technical_question"}
+	for i, text := range []string{"how do I reset", "my laptop is broken", "the app crashes"} {
+		result, err := clf.Classify(ctx, text)
+		if err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+		if result.Label != expected[i] {
+			t.Errorf("Classification %d: expected %q, got %q", i+1, expected[i], result.Label)
+		}
+	}
+
+	// The union-find root is unchanged; only the reported name follows usage
+	clusters := clf.Clusters()
+	if len(clusters) != 1 || clusters[0].Label != "tech_q" || clusters[0].Canonical != "technical_question" {
+		t.Errorf("Expected cluster tech_q named technical_question, got %+v", clusters)
+	}
+}
+
+func TestClassifier_CanonicalLabelFollowsCacheHits(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	dsu.Observe("tech_q")
+	dsu.Observe("tech_q")
+	dsu.Observe("technical_question")
+	dsu.Union(dsu.FindOrCreate("tech_q"), dsu.FindOrCreate("technical_question"))
+	dsu.SetCanonical("tech_q", "tech_q")
+
+	// Every text is a cached technical_question
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		return []types.VectorMatch{{ID: "1", Score: 0.99, Metadata: map[string]any{"label": "technical_question"}}}, nil
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence: &testutil.MockDSUPersistence{
+			LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+		},
+		CanonicalLabelStrategy: classifier.MostFrequentLabel{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	// Drawing level keeps the current name; overtaking it renames the cluster
+	expected := []string{"tech_q", "technical_question"}
+	for i, text := range []string{"how do I reset", "the app crashes"} {
+		result, err := clf.Classify(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+		if !result.CacheHit || result.Label != expected[i] {
+			t.Errorf("Hit %d: expected %q, got %q (cache hit %v)", i+1, expected[i], result.Label, result.CacheHit)
+		}
+	}
+}
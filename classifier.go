@@ -2,15 +2,21 @@ package classifier
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/FrenchMajesty/consistent-classifier/adapters"
 	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/internal/logging"
+	"github.com/FrenchMajesty/consistent-classifier/internal/tracing"
+	"github.com/FrenchMajesty/consistent-classifier/types"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Classifier performs text classification with vector caching and label clustering
@@ -23,14 +29,36 @@ type Classifier struct {
 	dsuPersist           DisjointSetPersistence
 	minSimilarityContent float32
 	minSimilarityLabel   float32
+	minSimilarityParent  float32
+	taxonomy             map[string]string
+	parentProposer       ParentProposer
+	canonicalStrategy    CanonicalLabelStrategy
+	mergeGuard           *MergeGuard
+	fewShotExamples      int
+	labelHints           LabelHintMode
+	labelHintCount       int
+	fallbackToCache      bool
+	tenant               string
 
 	// Metrics tracking
 	totalClassifications int
 	cacheHits            int
+	newLabels            int
 	metricsLock          sync.RWMutex
+	recorder             types.MetricsRecorder
+	observer             Observer
+	tracer               trace.Tracer
+	logger               *slog.Logger
+
+	// Label vector reconciliation
+	reconcileLock sync.Mutex
+	reconciled    bool               // Whether a full Reconcile has completed
+	reconcileStop context.CancelFunc // Stops the scheduled Reconcile, nil if not scheduled
+	reconcileDone chan struct{}
 
 	// Background task tracking for graceful shutdown
 	backgroundTasks sync.WaitGroup
+	pendingTasks    atomic.Int64
 	shutdownOnce    sync.Once
 	closing         bool
 	closeLock       sync.RWMutex
@@ -39,6 +67,7 @@ type Classifier struct {
 // NewClassifier creates a new Classifier with the given configuration
 func NewClassifier(cfg Config) (*Classifier, error) {
 	cfg.applyDefaults()
+	logger := logging.WithRequestCorrelation(cfg.Logger)
 
 	// Initialize clients
 	var embeddingClient EmbeddingClient
@@ -49,6 +78,13 @@ func NewClassifier(cfg Config) (*Classifier, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create default embedding client: %w", err)
 		}
+		if cfg.TracerProvider != nil {
+			client.SetTracerProvider(cfg.TracerProvider)
+		}
+		if cfg.MetricsRecorder != nil {
+			client.SetMetricsRecorder(cfg.MetricsRecorder)
+		}
+		client.SetLogger(logger)
 		embeddingClient = client
 	}
 
@@ -60,6 +96,16 @@ func NewClassifier(cfg Config) (*Classifier, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create default vector client (label): %w", err)
 		}
+		if cfg.TracerProvider != nil {
+			client.SetTracerProvider(cfg.TracerProvider)
+		}
+		if cfg.MetricsRecorder != nil {
+			client.SetMetricsRecorder(cfg.MetricsRecorder)
+		}
+		client.SetLogger(logger)
+		if err := matchDimension(client, embeddingClient, logger); err != nil {
+			return nil, fmt.Errorf("default vector client (label): %w", err)
+		}
 		vectorClientLabel = client
 	}
 
@@ -71,9 +117,27 @@ func NewClassifier(cfg Config) (*Classifier, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create default vector client (content): %w", err)
 		}
+		if cfg.TracerProvider != nil {
+			client.SetTracerProvider(cfg.TracerProvider)
+		}
+		if cfg.MetricsRecorder != nil {
+			client.SetMetricsRecorder(cfg.MetricsRecorder)
+		}
+		client.SetLogger(logger)
+		if err := matchDimension(client, embeddingClient, logger); err != nil {
+			return nil, fmt.Errorf("default vector client (content): %w", err)
+		}
 		vectorClientContent = client
 	}
 
+	embeddingClient = chain(embeddingClient, cfg.EmbeddingMiddleware)
+	vectorClientLabel = chain(vectorClientLabel, cfg.VectorMiddleware)
+	vectorClientContent = chain(vectorClientContent, cfg.VectorMiddleware)
+
+	if _, ok := vectorClientContent.(ExtendedVectorClient); cfg.Tenant != "" && !ok {
+		return nil, fmt.Errorf("tenant filtering requires the content client to implement ExtendedVectorClient: %w", ErrUnsupportedVectorClient)
+	}
+
 	var llmClient LLMClient
 	if cfg.LLMClient != nil {
 		llmClient = cfg.LLMClient
@@ -82,9 +146,18 @@ func NewClassifier(cfg Config) (*Classifier, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create default LLM client: %w", err)
 		}
+		if cfg.MetricsRecorder != nil {
+			client.SetMetricsRecorder(cfg.MetricsRecorder)
+		}
+		if cfg.TracerProvider != nil {
+			client.SetTracerProvider(cfg.TracerProvider)
+		}
+		client.SetLogger(logger)
 		llmClient = client
 	}
 
+	llmClient = chain(llmClient, cfg.LLMMiddleware)
+
 	var dsuPersist DisjointSetPersistence
 	if cfg.DSUPersistence != nil {
 		dsuPersist = cfg.DSUPersistence
@@ -98,7 +171,18 @@ func NewClassifier(cfg Config) (*Classifier, error) {
 		return nil, fmt.Errorf("failed to load DSU: %w", err)
 	}
 
-	return &Classifier{
+	if cfg.MetricsRecorder != nil {
+		cfg.MetricsRecorder.RecordLabelSets(dsu.Size(), dsu.CountSets())
+	}
+
+	if _, ok := vectorClientLabel.(ExtendedVectorClient); cfg.ReconcileInterval > 0 && !ok {
+		return nil, fmt.Errorf("scheduled reconcile requires the label client to implement ExtendedVectorClient: %w", ErrUnsupportedVectorClient)
+	}
+	if _, ok := vectorClientLabel.(ExtendedVectorClient); cfg.MergeGuard != nil && !ok {
+		return nil, fmt.Errorf("merge guards require the label client to implement ExtendedVectorClient: %w", ErrUnsupportedVectorClient)
+	}
+
+	c := &Classifier{
 		embedding:            embeddingClient,
 		vectorContent:        vectorClientContent,
 		vectorLabel:          vectorClientLabel,
@@ -107,35 +191,127 @@ func NewClassifier(cfg Config) (*Classifier, error) {
 		dsuPersist:           dsuPersist,
 		minSimilarityContent: cfg.MinSimilarityContent,
 		minSimilarityLabel:   cfg.MinSimilarityLabel,
-	}, nil
+		minSimilarityParent:  cfg.MinSimilarityParent,
+		taxonomy:             cfg.Taxonomy,
+		parentProposer:       cfg.ParentProposer,
+		canonicalStrategy:    cfg.CanonicalLabelStrategy,
+		mergeGuard:           cfg.MergeGuard,
+		fewShotExamples:      cfg.FewShotExamples,
+		labelHints:           cfg.LabelHints,
+		labelHintCount:       cfg.LabelHintCount,
+		fallbackToCache:      cfg.FallbackToCache,
+		tenant:               cfg.Tenant,
+		recorder:             cfg.MetricsRecorder,
+		observer:             cfg.Observer,
+		tracer:               tracing.Tracer(cfg.TracerProvider),
+		logger:               logger,
+	}
+
+	if cfg.ReconcileInterval > 0 {
+		ctx, stop := context.WithCancel(context.Background())
+		c.reconcileStop = stop
+		c.reconcileDone = make(chan struct{})
+		go func() {
+			defer close(c.reconcileDone)
+			c.runScheduledReconcile(ctx, cfg.ReconcileInterval)
+		}()
+	}
+
+	return c, nil
+}
+
+// matchDimension makes a default Pinecone adapter check vectors against the dimension of the
+// embedding client, if it reports one. Fails only if the index stores vectors of another dimension;
+// an index that can't be described within one request timeout is logged and checked per vector instead.
+func matchDimension(client *adapters.PineconeVectorAdapter, embedding EmbeddingClient, logger *slog.Logger) error {
+	sized, ok := embedding.(interface{ Dimensions() int })
+	if !ok || sized.Dimensions() == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adapters.DefaultPineconeTimeout)
+	defer cancel()
+	err := client.MatchDimension(ctx, sized.Dimensions())
+	if err != nil && !errors.Is(err, ErrDimensionMismatch) {
+		logger.Warn("could not verify vector index dimension", slog.Any("error", err))
+		return nil
+	}
+	return err
 }
 
 // Classify classifies the given text and returns the classification result
 func (c *Classifier) Classify(ctx context.Context, text string) (*Result, error) {
+	if RequestID(ctx) == "" {
+		ctx = WithRequestID(ctx, uuid.New().String())
+	}
+
+	ctx, span := tracing.Start(ctx, c.tracer, "classifier.Classify")
+	result, err := c.classify(ctx, text)
+	if result != nil {
+		span.SetAttributes(
+			tracing.AttrCacheHit.Bool(result.CacheHit),
+			tracing.AttrSimilarity.Float64(float64(result.Confidence)),
+			tracing.AttrLabel.String(result.Label),
+		)
+	}
+	tracing.End(span, err)
+
+	switch {
+	case err != nil:
+		c.metricsRecorder().RecordClassification(types.OutcomeError)
+		c.log().LogAttrs(ctx, slog.LevelDebug, "classification failed", slog.Any("error", err))
+	case result.Degraded:
+		c.metricsRecorder().RecordClassification(types.OutcomeDegraded)
+	case result.CacheHit:
+		c.metricsRecorder().RecordClassification(types.OutcomeHit)
+	default:
+		c.metricsRecorder().RecordClassification(types.OutcomeMiss)
+	}
+
+	if result != nil {
+		c.log().LogAttrs(ctx, slog.LevelDebug, "classification completed",
+			slog.String("label", result.Label),
+			slog.Bool("cache_hit", result.CacheHit),
+			slog.Bool("degraded", result.Degraded),
+			slog.Float64("similarity", float64(result.Confidence)),
+			slog.Duration("latency", result.UserFacingLatency),
+		)
+	}
+
+	return result, err
+}
+
+// classify runs the classification pipeline: embed, search cache, and on miss call the LLM and update the cache
+func (c *Classifier) classify(ctx context.Context, text string) (*Result, error) {
 	// Check if classifier is shutting down
 	c.closeLock.RLock()
 	if c.closing {
 		c.closeLock.RUnlock()
-		return nil, fmt.Errorf("classifier is shutting down")
+		return nil, ErrClosed
 	}
 	c.closeLock.RUnlock()
 
 	// Skip empty or whitespace-only text
 	text = strings.TrimSpace(text)
 	if text == "" {
-		return nil, fmt.Errorf("cannot classify empty text")
+		return nil, ErrEmptyInput
 	}
 
 	userFacingStart := time.Now()
 
 	// Step 1: Generate embedding for this text
-	embedding, err := c.embedding.GenerateEmbedding(ctx, text)
+	stageCtx, endStage := c.startStage(ctx, types.StageEmbed)
+	embedding, err := embed(stageCtx, c.embedding, text, types.EmbeddingInputQuery)
+	endStage(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
 	// Step 2: Search vector cache for similar text
-	matches, err := c.vectorContent.Search(ctx, embedding, 1)
+	// When few-shot prompting is enabled, the same search also supplies the examples
+	stageCtx, endStage = c.startStage(ctx, types.StageSearch)
+	matches, err := c.searchContent(stageCtx, embedding, max(1, c.fewShotExamples))
+	endStage(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search vector cache: %w", err)
 	}
@@ -146,15 +322,19 @@ func (c *Classifier) Classify(ctx context.Context, text string) (*Result, error)
 		userFacingLatency := time.Since(userFacingStart)
 		label, ok := matches[0].Metadata["label"].(string)
 		if !ok {
-			return nil, fmt.Errorf("cached vector missing label metadata")
+			return nil, fmt.Errorf("%w: %s", ErrCorruptCacheEntry, matches[0].ID)
 		}
 
 		c.recordCacheHit()
+		c.dsu.Observe(label)
+		c.refreshCanonicalOnHit(ctx, label)
 
-		rootLabel := c.dsu.FindLabel(c.dsu.FindOrCreate(label))
+		resultLabel := c.resultLabel(label)
+		c.observe().OnCacheHit(ctx, text, resultLabel, matches[0].Score)
 
 		return &Result{
-			Label:             rootLabel,
+			Label:             resultLabel,
+			Path:              c.labelPath(label, resultLabel),
 			CacheHit:          true,
 			Confidence:        matches[0].Score,
 			UserFacingLatency: userFacingLatency,
@@ -163,36 +343,76 @@ func (c *Classifier) Classify(ctx context.Context, text string) (*Result, error)
 	}
 
 	// Cache MISS - call LLM for classification
-	label, err := c.llm.Classify(ctx, text)
+	c.observe().OnCacheMiss(ctx, text)
+	stageCtx, endStage = c.startStage(ctx, types.StageLLM)
+	label, err := c.classifyWithLLM(stageCtx, text, embedding, matches)
+	// Retry with shorter input, and without examples, while it exceeds the model's context length
+	llmText := text
+	for attempt := 0; errors.Is(err, ErrContextLength) && attempt < maxContextTruncations; attempt++ {
+		llmText = truncateText(llmText, len([]rune(llmText))/2)
+		c.log().LogAttrs(ctx, slog.LevelWarn, "input exceeds LLM context length, retrying truncated",
+			slog.Int("attempt", attempt+1),
+			slog.Int("length", len(llmText)),
+		)
+		label, err = c.classifyWithLLM(stageCtx, llmText, embedding, nil)
+	}
+	endStage(err)
 	if err != nil {
+		// While the LLM is unavailable, serve the closest cached label rather than failing
+		if c.fallbackToCache && errors.Is(err, ErrCircuitOpen) && len(matches) > 0 {
+			return c.degradedResult(ctx, matches[0], userFacingStart)
+		}
 		return nil, fmt.Errorf("failed to classify with LLM: %w", err)
 	}
 
 	// Validate label from LLM
 	label = strings.TrimSpace(label)
 	if label == "" {
-		return nil, fmt.Errorf("LLM returned empty label")
+		return nil, ErrEmptyLabel
 	}
 
 	userFacingLatency := time.Since(userFacingStart)
-	c.recordClassification()
+	// Only one of several concurrent classifications adding the same label sees it as new
+	newLabel := c.dsu.Observe(label)
+	c.recordClassification(newLabel)
+	c.dsu.SetOrigin(label, disjoint_set.OriginLLM)
+	c.observe().OnLLMLabel(ctx, text, label)
 
 	// Track background task for graceful shutdown
-	c.backgroundTasks.Add(1)
-	defer c.backgroundTasks.Done()
+	c.beginBackgroundTask()
+	defer c.endBackgroundTask()
 
 	// Background processing - run asynchronously but wait for completion
 	backgroundStart := time.Now()
-	err = c.processBackgroundTasks(ctx, text, embedding, label)
+	backgroundCtx, span := tracing.Start(ctx, c.tracer, "classifier.background", tracing.AttrLabel.String(label))
+	err = c.processBackgroundTasks(backgroundCtx, text, embedding, label)
+	tracing.End(span, err)
+	backgroundLatency := time.Since(backgroundStart)
 	if err != nil {
 		// Don't fail the classification, just log the error
 		// In production you might want to handle this differently
-		log.Printf("Error: background processing failed: %v\n", err)
+		c.log().LogAttrs(ctx, slog.LevelError, "background processing failed",
+			slog.String("stage", "background"),
+			slog.String("label", label),
+			slog.Duration("latency", backgroundLatency),
+			slog.Any("error", err),
+		)
+		c.observe().OnBackgroundError(ctx, label, err)
+	}
+	c.metricsRecorder().RecordLabelSets(c.dsu.Size(), c.dsu.CountSets())
+	if newLabel && err == nil && c.dsu.SetSize(label) == 1 {
+		c.observe().OnNewCluster(ctx, label)
+	}
+
+	// Without a strategy the LLM's label is returned as is, even when it joined another cluster
+	resultLabel := label
+	if c.canonicalStrategy != nil {
+		resultLabel = c.resultLabel(label)
 	}
-	backgroundLatency := time.Since(backgroundStart)
 
 	return &Result{
-		Label:             label,
+		Label:             resultLabel,
+		Path:              c.labelPath(label, resultLabel),
 		CacheHit:          false,
 		Confidence:        0,
 		UserFacingLatency: userFacingLatency,
@@ -200,6 +420,122 @@ func (c *Classifier) Classify(ctx context.Context, text string) (*Result, error)
 	}, nil
 }
 
+// maxContextTruncations is how many times input is halved and retried when it exceeds the LLM's context length
+const maxContextTruncations = 3
+
+// truncateText shortens text to at most n runes
+func truncateText(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
+
+// degradedResult returns the label of a below-threshold cache match, used when the LLM circuit breaker is open
+func (c *Classifier) degradedResult(ctx context.Context, match types.VectorMatch, userFacingStart time.Time) (*Result, error) {
+	label, ok := match.Metadata["label"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptCacheEntry, match.ID)
+	}
+
+	resultLabel := c.resultLabel(label)
+	c.log().LogAttrs(ctx, slog.LevelWarn, "LLM unavailable, serving closest cached label",
+		slog.String("label", resultLabel),
+		slog.Float64("similarity", float64(match.Score)),
+	)
+
+	return &Result{
+		Label:             resultLabel,
+		Path:              c.labelPath(label, resultLabel),
+		Confidence:        match.Score,
+		Degraded:          true,
+		UserFacingLatency: time.Since(userFacingStart),
+	}, nil
+}
+
+// classifyWithLLM calls the LLM, passing retrieved examples and label hints when enabled
+func (c *Classifier) classifyWithLLM(ctx context.Context, text string, embedding []float32, matches []types.VectorMatch) (string, error) {
+	prompted, ok := c.llm.(PromptedLLMClient)
+	if !ok || (c.fewShotExamples == 0 && c.labelHints == LabelHintsNone) {
+		return c.llm.Classify(ctx, text)
+	}
+
+	data := types.PromptData{Text: text}
+
+	if c.fewShotExamples > 0 {
+		data.Examples = c.buildExamples(matches)
+	}
+
+	labels, err := c.buildLabelHints(ctx, embedding)
+	if err != nil {
+		return "", fmt.Errorf("failed to load label hints: %w", err)
+	}
+	data.Labels = labels
+
+	return prompted.ClassifyWithPrompt(ctx, data)
+}
+
+// buildLabelHints returns the labels to suggest to the LLM, as Classify reports them to callers
+func (c *Classifier) buildLabelHints(ctx context.Context, embedding []float32) ([]string, error) {
+	var roots []string
+	switch c.labelHints {
+	case LabelHintsAll:
+		roots = c.dsu.Roots()
+	case LabelHintsNearest:
+		matches, err := c.vectorLabel.Search(ctx, embedding, c.labelHintCount)
+		if err != nil {
+			return nil, err
+		}
+
+		// Resolve through the DSU since stored root metadata may predate later merges
+		roots = make([]string, 0, len(matches))
+		for _, match := range matches {
+			label, _ := match.Metadata["root"].(string)
+			if root, ok := c.dsu.Root(label); ok {
+				roots = append(roots, root)
+			}
+		}
+	default:
+		return nil, nil
+	}
+
+	// Clusters sharing a canonical label are suggested once
+	seen := make(map[string]bool, len(roots))
+	labels := make([]string, 0, len(roots))
+	for _, root := range roots {
+		label := c.resultLabel(root)
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// buildExamples converts cached matches into few-shot examples labelled with their DSU root
+func (c *Classifier) buildExamples(matches []types.VectorMatch) []types.LabeledExample {
+	examples := make([]types.LabeledExample, 0, len(matches))
+	for _, match := range matches {
+		exampleText, ok := match.Metadata["vector_text"].(string)
+		if !ok || exampleText == "" {
+			continue
+		}
+		label, ok := match.Metadata["label"].(string)
+		if !ok || label == "" {
+			continue
+		}
+
+		rootLabel := c.dsu.FindOrCreateRoot(label)
+
+		examples = append(examples, types.LabeledExample{
+			Text:  exampleText,
+			Label: rootLabel,
+		})
+	}
+	return examples
+}
+
 // processBackgroundTasks handles label clustering and vector caching
 func (c *Classifier) processBackgroundTasks(ctx context.Context, text string, embedding []float32, label string) error {
 	// Check if context is already cancelled
@@ -222,8 +558,15 @@ func (c *Classifier) processBackgroundTasks(ctx context.Context, text string, em
 			return
 		default:
 		}
-		if err := c.updateLabelClustering(ctx, label); err != nil {
+		ctx, endStage := c.startStage(ctx, types.StageCluster)
+		err := c.updateLabelClustering(ctx, label)
+		endStage(err)
+		if err != nil {
 			errChan <- fmt.Errorf("label clustering failed: %w", err)
+			return
+		}
+		if err := c.refreshCanonicalLabel(ctx, label); err != nil {
+			errChan <- fmt.Errorf("canonical label selection failed: %w", err)
 		}
 	}()
 
@@ -237,7 +580,10 @@ func (c *Classifier) processBackgroundTasks(ctx context.Context, text string, em
 			return
 		default:
 		}
-		if err := c.cacheTextEmbedding(ctx, text, embedding, label); err != nil {
+		ctx, endStage := c.startStage(ctx, types.StageUpsert)
+		err := c.cacheTextEmbedding(ctx, text, embedding, label)
+		endStage(err)
+		if err != nil {
 			errChan <- fmt.Errorf("text caching failed: %w", err)
 		}
 	}()
@@ -252,7 +598,10 @@ func (c *Classifier) processBackgroundTasks(ctx context.Context, text string, em
 			return
 		default:
 		}
-		if err := c.cacheLabelEmbedding(ctx, label); err != nil {
+		ctx, endStage := c.startStage(ctx, types.StageUpsert)
+		err := c.cacheLabelEmbedding(ctx, label)
+		endStage(err)
+		if err != nil {
 			errChan <- fmt.Errorf("label caching failed: %w", err)
 		}
 	}()
@@ -279,7 +628,7 @@ func (c *Classifier) updateLabelClustering(ctx context.Context, label string) er
 	}
 
 	// Generate embedding for the label
-	labelEmbedding, err := c.embedding.GenerateEmbedding(ctx, label)
+	labelEmbedding, err := embed(ctx, c.embedding, label, types.EmbeddingInputQuery)
 	if err != nil {
 		return err
 	}
@@ -290,31 +639,128 @@ func (c *Classifier) updateLabelClustering(ctx context.Context, label string) er
 		return err
 	}
 
-	// Find the root label
+	// Find the root label through the DSU, since the stored root may since have been merged or
+	// retired. A root the DSU doesn't know is never merged into, so retired labels stay retired.
 	rootLabel := label
 	if len(matches) > 0 && matches[0].Score >= c.minSimilarityLabel {
-		root, ok := matches[0].Metadata["root"].(string)
-		if ok {
-			rootLabel = root
+		for _, key := range []string{"root", "label"} {
+			stored, _ := matches[0].Metadata[key].(string)
+			if root, ok := c.dsu.Root(stored); ok {
+				rootLabel = root
+				break
+			}
+		}
+	}
+
+	// Check the label against the rest of the cluster, not just its nearest label. A label that is
+	// already a member was checked when it joined.
+	currentRoot, _ := c.dsu.Root(label)
+	clusterRoot, _ := c.dsu.Root(rootLabel)
+	if c.mergeGuard != nil && rootLabel != label && clusterRoot != "" && currentRoot != clusterRoot {
+		reason, err := c.checkMerge(ctx, label, labelEmbedding, rootLabel)
+		if err != nil {
+			return err
 		}
+		if reason != "" {
+			c.logRejectedMerge(ctx, label, c.resultLabel(rootLabel), matches[0].Score, reason)
+			rootLabel = label
+		}
+	}
+
+	// Union the label with the root label in DSU, recording why it was merged
+	if rootLabel == label {
+		c.dsu.FindOrCreate(label)
+	} else {
+		if c.dsu.MergeLabels(label, rootLabel, matches[0].Score) {
+			c.observe().OnLabelMerged(ctx, label, rootLabel, matches[0].Score)
+		}
+	}
+
+	return c.assignParent(ctx, label, matches)
+}
+
+// assignParent sets the parent category of the label's cluster. A taxonomy entry always wins;
+// otherwise an existing parent is kept, then the looser similarity threshold and finally the proposer are tried.
+func (c *Classifier) assignParent(ctx context.Context, label string, matches []types.VectorMatch) error {
+	clusterRoot := c.dsu.FindOrCreateRoot(label)
+
+	for _, candidate := range []string{label, clusterRoot} {
+		if parent, ok := c.taxonomy[candidate]; ok {
+			c.dsu.SetParent(label, parent)
+			return nil
+		}
+	}
+
+	if c.dsu.Parent(label) != "" {
+		return nil
+	}
+
+	// A nearby but not-quite-matching label lends its parent, or becomes the parent itself
+	if c.minSimilarityParent > 0 && len(matches) > 0 &&
+		matches[0].Score >= c.minSimilarityParent && matches[0].Score < c.minSimilarityLabel {
+		if neighbour, ok := matches[0].Metadata["root"].(string); ok && neighbour != "" {
+			neighbourRoot := c.dsu.FindOrCreateRoot(neighbour)
+			parent := c.dsu.Parent(neighbourRoot)
+			if parent == "" {
+				parent = neighbourRoot
+			}
+			if parent != clusterRoot {
+				c.dsu.SetParent(label, parent)
+			}
+			return nil
+		}
+	}
+
+	if c.parentProposer == nil {
+		return nil
 	}
 
-	// Union the label with the root label in DSU
-	c.dsu.Union(c.dsu.FindOrCreate(rootLabel), c.dsu.FindOrCreate(label))
+	parent, err := c.parentProposer.ProposeParent(ctx, clusterRoot, c.dsu.Categories())
+	if err != nil {
+		return fmt.Errorf("failed to propose parent category: %w", err)
+	}
+
+	parent = strings.TrimSpace(parent)
+	if parent != "" && parent != clusterRoot {
+		c.dsu.SetParent(label, parent)
+	}
 
 	return nil
 }
 
-// cacheTextEmbedding stores the text embedding in the vector database
+// cacheTextEmbedding stores the text embedding in the vector database. Clients that distinguish
+// input types embed the text again as a document; others reuse the lookup embedding.
 func (c *Classifier) cacheTextEmbedding(ctx context.Context, text string, embedding []float32, label string) error {
+	if _, ok := c.embedding.(InputTypeEmbeddingClient); ok {
+		var err error
+		embedding, err = embed(ctx, c.embedding, text, types.EmbeddingInputDocument)
+		if err != nil {
+			return err
+		}
+	}
+
 	id := uuid.New().String()
 	metadata := map[string]any{
 		"vector_text": text,
 		"label":       label,
 	}
+	if c.tenant != "" {
+		metadata["tenant"] = c.tenant
+	}
 	return c.vectorContent.Upsert(ctx, id, embedding, metadata)
 }
 
+// searchContent searches the text cache, only matching the configured tenant's vectors if set
+func (c *Classifier) searchContent(ctx context.Context, embedding []float32, topK int) ([]types.VectorMatch, error) {
+	if c.tenant == "" {
+		return c.vectorContent.Search(ctx, embedding, topK)
+	}
+
+	// NewClassifier checks the client supports filtering when a tenant is set
+	filter := map[string]any{"tenant": map[string]any{"$eq": c.tenant}}
+	return c.vectorContent.(ExtendedVectorClient).SearchWithFilter(ctx, embedding, topK, filter)
+}
+
 // cacheLabelEmbedding stores the label embedding in the vector database
 func (c *Classifier) cacheLabelEmbedding(ctx context.Context, label string) error {
 	// Skip empty or whitespace-only labels
@@ -324,17 +770,13 @@ func (c *Classifier) cacheLabelEmbedding(ctx context.Context, label string) erro
 	}
 
 	// Generate embedding for the label
-	labelEmbedding, err := c.embedding.GenerateEmbedding(ctx, label)
+	labelEmbedding, err := embed(ctx, c.embedding, label, types.EmbeddingInputDocument)
 	if err != nil {
 		return err
 	}
 
 	// Find root label from DSU
-	rootIdx := c.dsu.FindOrCreate(label)
-	rootLabel := c.dsu.FindLabel(rootIdx)
-	if rootLabel == "" {
-		rootLabel = label
-	}
+	rootLabel := c.dsu.FindOrCreateRoot(label)
 
 	metadata := map[string]any{
 		"vector_text": label,
@@ -344,6 +786,14 @@ func (c *Classifier) cacheLabelEmbedding(ctx context.Context, label string) erro
 	return c.vectorLabel.Upsert(ctx, label, labelEmbedding, metadata)
 }
 
+// embed generates an embedding for text, passing the input type if the client supports it
+func embed(ctx context.Context, client EmbeddingClient, text string, inputType types.EmbeddingInputType) ([]float32, error) {
+	if typed, ok := client.(InputTypeEmbeddingClient); ok && inputType != types.EmbeddingInputDefault {
+		return typed.GenerateEmbeddingForInput(ctx, text, inputType)
+	}
+	return client.GenerateEmbedding(ctx, text)
+}
+
 // SaveDSU saves the current DSU state to persistent storage
 // This method is thread-safe and waits for any pending background tasks to complete
 func (c *Classifier) SaveDSU() error {
@@ -363,6 +813,12 @@ func (c *Classifier) Close() error {
 		c.closing = true
 		c.closeLock.Unlock()
 
+		// Stop scheduled reconciles, letting a running one finish
+		if c.reconcileStop != nil {
+			c.reconcileStop()
+			<-c.reconcileDone
+		}
+
 		// Wait for all background tasks to complete
 		c.backgroundTasks.Wait()
 
@@ -383,10 +839,19 @@ func (c *Classifier) GetMetrics() Metrics {
 		cacheHitRate = float32(c.cacheHits) / float32(c.totalClassifications) * 100
 	}
 
+	var newLabelsPer1K float32
+	if c.totalClassifications > 0 {
+		newLabelsPer1K = float32(c.newLabels) / float32(c.totalClassifications) * 1000
+	}
+
 	return Metrics{
-		UniqueLabels:    c.dsu.Size(),
-		ConvergedLabels: c.dsu.CountSets(),
-		CacheHitRate:    cacheHitRate,
+		UniqueLabels:         c.dsu.Size(),
+		ConvergedLabels:      c.dsu.CountSets(),
+		CacheHitRate:         cacheHitRate,
+		Classifications:      c.totalClassifications,
+		NewLabels:            c.newLabels,
+		NewLabelsPer1K:       newLabelsPer1K,
+		BackgroundQueueDepth: int(c.pendingTasks.Load()),
 	}
 }
 
@@ -399,8 +864,49 @@ func (c *Classifier) recordCacheHit() {
 }
 
 // recordClassification records a classification (cache miss) for metrics
-func (c *Classifier) recordClassification() {
+func (c *Classifier) recordClassification(newLabel bool) {
 	c.metricsLock.Lock()
 	defer c.metricsLock.Unlock()
 	c.totalClassifications++
+	if newLabel {
+		c.newLabels++
+	}
+}
+
+// log returns the configured logger, or slog.Default() if none is set
+func (c *Classifier) log() *slog.Logger {
+	if c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
+}
+
+// metricsRecorder returns the configured recorder, or a no-op recorder if none is set
+func (c *Classifier) metricsRecorder() types.MetricsRecorder {
+	if c.recorder == nil {
+		return types.NopMetricsRecorder{}
+	}
+	return c.recorder
+}
+
+// startStage starts a span for a pipeline stage. The returned function ends the span and records the stage latency.
+func (c *Classifier) startStage(ctx context.Context, stage string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, c.tracer, "classifier."+stage)
+	return ctx, func(err error) {
+		c.metricsRecorder().RecordStageLatency(stage, time.Since(start))
+		tracing.End(span, err)
+	}
+}
+
+// beginBackgroundTask tracks a background task for graceful shutdown and queue depth
+func (c *Classifier) beginBackgroundTask() {
+	c.backgroundTasks.Add(1)
+	c.metricsRecorder().RecordQueueDepth(int(c.pendingTasks.Add(1)))
+}
+
+// endBackgroundTask marks a background task started with beginBackgroundTask as done
+func (c *Classifier) endBackgroundTask() {
+	c.metricsRecorder().RecordQueueDepth(int(c.pendingTasks.Add(-1)))
+	c.backgroundTasks.Done()
 }
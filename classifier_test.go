@@ -3,6 +3,8 @@ package classifier_test
 import (
 	"context"
 	"errors"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -590,3 +592,356 @@ func TestClassifier_CacheLabelEmbedding_RootLookup(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 	})
 }
+
+// TestClassifier_FewShotExamples tests that nearest cached texts are passed to the LLM on cache miss
+func TestClassifier_FewShotExamples(t *testing.T) {
+	existingDSU := disjoint_set.NewDSU()
+	existingDSU.Add("billing_issue")
+	existingDSU.Add("payment_problem")
+	existingDSU.Union(0, 1)
+
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		if topK != 3 {
+			t.Errorf("Expected topK 3, got %d", topK)
+		}
+		return []types.VectorMatch{
+			{ID: "a", Score: 0.70, Metadata: map[string]any{"vector_text": "my card was charged twice", "label": "payment_problem"}},
+			{ID: "b", Score: 0.60, Metadata: map[string]any{"vector_text": "thanks a lot", "label": "gratitude"}},
+			{ID: "c", Score: 0.50, Metadata: map[string]any{"label": "missing_text"}},
+		}, nil
+	}
+
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyWithPromptFunc: func(ctx context.Context, data types.PromptData) (string, error) {
+			return "billing_issue", nil
+		},
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           mockLLM,
+		DSUPersistence: &testutil.MockDSUPersistence{
+			LoadFunc: func() (*disjoint_set.DSU, error) { return existingDSU, nil },
+		},
+		FewShotExamples: 3,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	result, err := clf.Classify(context.Background(), "I was billed twice")
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	if result.CacheHit {
+		t.Error("Expected cache miss, got cache hit")
+	}
+
+	if mockLLM.LastPrompt == nil {
+		t.Fatal("Expected ClassifyWithPrompt to be called")
+	}
+
+	examples := mockLLM.LastPrompt.Examples
+	if len(examples) != 2 {
+		t.Fatalf("Expected 2 examples (entry without text skipped), got %d", len(examples))
+	}
+
+	// Example labels are resolved to their DSU root
+	if examples[0].Label != "billing_issue" {
+		t.Errorf("Expected example label to be root 'billing_issue', got '%s'", examples[0].Label)
+	}
+
+	if examples[1].Text != "thanks a lot" || examples[1].Label != "gratitude" {
+		t.Errorf("Unexpected second example: %+v", examples[1])
+	}
+}
+
+// TestClassifier_FewShotDisabled tests that the plain Classify path is used when few-shot is off
+func TestClassifier_FewShotDisabled(t *testing.T) {
+	mockLLM := &testutil.MockLLMClient{}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           mockLLM,
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	if _, err := clf.Classify(context.Background(), "test text"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	if mockLLM.LastPrompt != nil {
+		t.Error("Expected ClassifyWithPrompt not to be called when FewShotExamples is 0")
+	}
+}
+
+// TestClassifier_LabelHints tests that existing cluster labels are suggested to the LLM
+func TestClassifier_LabelHints(t *testing.T) {
+	newDSU := func() *disjoint_set.DSU {
+		dsu := disjoint_set.NewDSU()
+		dsu.Add("refund_request")
+		dsu.Add("money_back")
+		dsu.Add("greeting")
+		dsu.Union(0, 1)
+		return dsu
+	}
+
+	t.Run("all roots", func(t *testing.T) {
+		dsu := newDSU()
+		mockLLM := &testutil.MockLLMClient{}
+
+		clf, err := classifier.NewClassifier(classifier.Config{
+			EmbeddingClient:     &testutil.MockEmbeddingClient{},
+			VectorClientContent: testutil.NewMockVectorClient(),
+			VectorClientLabel:   testutil.NewMockVectorClient(),
+			LLMClient:           mockLLM,
+			DSUPersistence: &testutil.MockDSUPersistence{
+				LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+			},
+			LabelHints: classifier.LabelHintsAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create classifier: %v", err)
+		}
+
+		if _, err := clf.Classify(context.Background(), "test text"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+
+		if mockLLM.LastPrompt == nil {
+			t.Fatal("Expected ClassifyWithPrompt to be called")
+		}
+
+		labels := mockLLM.LastPrompt.Labels
+		if len(labels) != 2 || labels[0] != "greeting" || labels[1] != "refund_request" {
+			t.Errorf("Expected root labels [greeting refund_request], got %v", labels)
+		}
+	})
+
+	t.Run("nearest roots", func(t *testing.T) {
+		dsu := newDSU()
+		mockLLM := &testutil.MockLLMClient{}
+
+		mockVectorLabel := testutil.NewMockVectorClient()
+		mockVectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+			// Background label clustering searches with topK 1
+			if topK == 1 {
+				return []types.VectorMatch{}, nil
+			}
+			if topK != 2 {
+				t.Errorf("Expected topK 2, got %d", topK)
+			}
+			// Stale root metadata is resolved through the DSU and deduplicated
+			return []types.VectorMatch{
+				{ID: "money_back", Score: 0.9, Metadata: map[string]any{"root": "money_back"}},
+				{ID: "refund_request", Score: 0.8, Metadata: map[string]any{"root": "refund_request"}},
+			}, nil
+		}
+
+		clf, err := classifier.NewClassifier(classifier.Config{
+			EmbeddingClient:     &testutil.MockEmbeddingClient{},
+			VectorClientContent: testutil.NewMockVectorClient(),
+			VectorClientLabel:   mockVectorLabel,
+			LLMClient:           mockLLM,
+			DSUPersistence: &testutil.MockDSUPersistence{
+				LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+			},
+			LabelHints:     classifier.LabelHintsNearest,
+			LabelHintCount: 2,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create classifier: %v", err)
+		}
+
+		if _, err := clf.Classify(context.Background(), "test text"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+
+		labels := mockLLM.LastPrompt.Labels
+		if len(labels) != 1 || labels[0] != "refund_request" {
+			t.Errorf("Expected root labels [refund_request], got %v", labels)
+		}
+	})
+
+	t.Run("canonical labels", func(t *testing.T) {
+		dsu := newDSU()
+		dsu.SetCanonical("money_back", "money_back")
+		mockLLM := &testutil.MockLLMClient{}
+
+		clf, err := classifier.NewClassifier(classifier.Config{
+			EmbeddingClient:     &testutil.MockEmbeddingClient{},
+			VectorClientContent: testutil.NewMockVectorClient(),
+			VectorClientLabel:   testutil.NewMockVectorClient(),
+			LLMClient:           mockLLM,
+			DSUPersistence: &testutil.MockDSUPersistence{
+				LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+			},
+			LabelHints:             classifier.LabelHintsAll,
+			CanonicalLabelStrategy: classifier.MostFrequentLabel{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create classifier: %v", err)
+		}
+
+		if _, err := clf.Classify(context.Background(), "test text"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+
+		// The LLM is offered the labels callers see, not the union-find roots
+		labels := mockLLM.LastPrompt.Labels
+		if len(labels) != 2 || labels[0] != "greeting" || labels[1] != "money_back" {
+			t.Errorf("Expected canonical labels [greeting money_back], got %v", labels)
+		}
+	})
+}
+
+// TestClassifier_NewLabelMetrics tests that newly created labels are tracked
+func TestClassifier_NewLabelMetrics(t *testing.T) {
+	labels := []string{"greeting", "greeting", "farewell", "greeting"}
+	call := 0
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			label := labels[call]
+			call++
+			return label, nil
+		},
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           mockLLM,
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	for range labels {
+		if _, err := clf.Classify(context.Background(), "test text"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+	}
+
+	metrics := clf.GetMetrics()
+	if metrics.Classifications != 4 {
+		t.Errorf("Expected 4 classifications, got %d", metrics.Classifications)
+	}
+
+	if metrics.NewLabels != 2 {
+		t.Errorf("Expected 2 new labels, got %d", metrics.NewLabels)
+	}
+
+	if metrics.NewLabelsPer1K != 500 {
+		t.Errorf("Expected 500 new labels per 1k, got %f", metrics.NewLabelsPer1K)
+	}
+}
+
+// TestClassifier_EmbeddingInputTypes tests that lookups are embedded as queries and cache writes as documents
+func TestClassifier_EmbeddingInputTypes(t *testing.T) {
+	mockEmbedding := &testutil.MockInputTypeEmbeddingClient{}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     mockEmbedding,
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+				return "greeting", nil
+			},
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	if _, err := clf.Classify(context.Background(), "hello there"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if err := clf.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	queries := mockEmbedding.Texts(types.EmbeddingInputQuery)
+	documents := mockEmbedding.Texts(types.EmbeddingInputDocument)
+	if !slices.Contains(queries, "hello there") || !slices.Contains(queries, "greeting") {
+		t.Errorf("Expected the text and label to be looked up as queries, got %v", queries)
+	}
+	if !slices.Contains(documents, "hello there") || !slices.Contains(documents, "greeting") {
+		t.Errorf("Expected the text and label to be cached as documents, got %v", documents)
+	}
+}
+
+func TestClassifier_Tenant(t *testing.T) {
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		// Another tenant's text, which must not be served as a hit
+		return []types.VectorMatch{{ID: "other", Score: 0.99, Metadata: map[string]any{"label": "other_label", "tenant": "globex"}}}, nil
+	}
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			return "greeting", nil
+		},
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           mockLLM,
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		Tenant:              "acme",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	result, err := clf.Classify(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if result.CacheHit || result.Label != "greeting" {
+		t.Errorf("Expected a cache miss labelled by the LLM, got %+v", result)
+	}
+	if err := clf.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	expectedFilter := map[string]any{"tenant": map[string]any{"$eq": "acme"}}
+	if !reflect.DeepEqual(mockVectorContent.LastFilter, expectedFilter) {
+		t.Errorf("Expected filter %v, got %v", expectedFilter, mockVectorContent.LastFilter)
+	}
+	for id, stored := range mockVectorContent.Storage {
+		if stored.Metadata["tenant"] != "acme" {
+			t.Errorf("Expected cached text %s to be tagged with the tenant, got %v", id, stored.Metadata)
+		}
+	}
+}
+
+func TestClassifier_TenantRequiresExtendedVectorClient(t *testing.T) {
+	// Embedding the interface hides the mock's extended methods
+	searchOnly := struct{ classifier.VectorClient }{testutil.NewMockVectorClient()}
+
+	_, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: searchOnly,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		Tenant:              "acme",
+	})
+	if err == nil {
+		t.Fatal("Expected an error for a tenant without filtered search support")
+	}
+}
@@ -1,13 +1,38 @@
 package classifier
 
+import (
+	"log/slog"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
 const (
 	// DefaultMinSimilarity is the default threshold for vector similarity matching
 	DefaultMinSimilarity = 0.80
 
+	// DefaultLabelHintCount is the default number of nearest root labels suggested to the LLM
+	DefaultLabelHintCount = 10
+
 	// DefaultDSUFilePath is the default location for DSU state persistence
 	DefaultDSUFilePath = "./dsu_state.bin"
 )
 
+// LabelHintMode selects which existing root labels are suggested to the LLM on a cache miss
+type LabelHintMode int
+
+const (
+	// LabelHintsNone does not suggest existing labels
+	LabelHintsNone LabelHintMode = iota
+
+	// LabelHintsAll suggests the label of every cluster in the DSU
+	LabelHintsAll
+
+	// LabelHintsNearest suggests the labels of the clusters nearest to the input by label-vector search
+	LabelHintsNearest
+)
+
 // Config holds configuration for the Classifier
 type Config struct {
 	// EmbeddingClient generates embeddings for text. If nil, uses the default (Voyage AI).
@@ -23,12 +48,80 @@ type Config struct {
 	BaseUrl     string
 	Temperature *float32 // Optional temperature for LLM. If nil, uses model default.
 
+	// EmbeddingMiddleware, VectorMiddleware and LLMMiddleware wrap the clients, default or custom, in
+	// order: the first is the outermost. VectorMiddleware wraps both the label and content clients.
+	// Middleware hides the optional client extensions unless it preserves them, as InterceptEmbedding,
+	// InterceptVector and InterceptLLM do.
+	EmbeddingMiddleware []EmbeddingMiddleware
+	VectorMiddleware    []VectorMiddleware
+	LLMMiddleware       []LLMMiddleware
+
+	// MetricsRecorder receives classification outcomes, stage latencies, queue depth and DSU size. Optional.
+	// It is also wired into the default adapters for token usage, retry and hedge counts.
+	MetricsRecorder types.MetricsRecorder
+
+	// Observer receives classification lifecycle events: cache hits and misses, LLM labels, label
+	// merges, new clusters and background errors. Optional.
+	Observer Observer
+
+	// TracerProvider enables OpenTelemetry spans for each classify stage, background task, retry attempt
+	// and the default adapters' requests. If nil, tracing is disabled.
+	TracerProvider trace.TracerProvider
+
+	// Logger receives structured logs from the classifier and default adapters, with the request ID
+	// from the context attached. If nil, uses slog.Default().
+	Logger *slog.Logger
+
 	// DSUPersistence handles loading/saving the label clustering state. If nil, uses file-based persistence at ./dsu_state.bin
 	DSUPersistence DisjointSetPersistence
 
 	// MinSimilarity is the threshold for vector similarity matching (0.0 to 1.0). If 0, uses DefaultMinSimilarity.
 	MinSimilarityContent float32
 	MinSimilarityLabel   float32
+
+	// MergeGuard checks a label against several members of a cluster before merging it, preventing chains
+	// of loosely related labels from forming one cluster. Requires VectorClientLabel to implement
+	// ExtendedVectorClient. If nil, a label merges when its nearest label reaches MinSimilarityLabel.
+	MergeGuard *MergeGuard
+
+	// MinSimilarityParent is a looser label-similarity threshold for grouping clusters under a parent category.
+	// A nearest label scoring between MinSimilarityParent and MinSimilarityLabel shares its parent. If 0, disabled.
+	MinSimilarityParent float32
+
+	// Taxonomy pins labels to parent categories (label -> parent). Takes precedence over other parent sources.
+	Taxonomy map[string]string
+
+	// ParentProposer proposes parent categories for new clusters that got none from the taxonomy or threshold. Optional.
+	ParentProposer ParentProposer
+
+	// CanonicalLabelStrategy chooses which label of a cluster Classify returns, e.g. MostFrequentLabel{}.
+	// If nil, Classify returns the cluster's union-find root.
+	CanonicalLabelStrategy CanonicalLabelStrategy
+
+	// FewShotExamples is the number of nearest cached texts (with their root labels) passed to the
+	// LLM as examples on a cache miss. Requires a PromptedLLMClient. If 0, no examples are retrieved.
+	FewShotExamples int
+
+	// LabelHints passes existing cluster labels to the LLM so it reuses one when it fits. Clusters are
+	// suggested under the label Classify returns for them. Requires a PromptedLLMClient.
+	LabelHints LabelHintMode
+
+	// LabelHintCount is the number of labels suggested with LabelHintsNearest. If 0, uses DefaultLabelHintCount.
+	LabelHintCount int
+
+	// Tenant scopes the text cache to one tenant: cached texts are tagged with it and cache searches
+	// only match the tenant's texts. Labels and clusters are shared. Requires VectorClientContent to
+	// implement ExtendedVectorClient. If empty, the cache is shared.
+	Tenant string
+
+	// ReconcileInterval runs an incremental Reconcile at this interval until Close, logging the result.
+	// Each run is cancelled if it takes longer than the interval.
+	// Requires VectorClientLabel to implement ExtendedVectorClient. If 0, Reconcile only runs when called.
+	ReconcileInterval time.Duration
+
+	// FallbackToCache runs in cache-only mode while the LLM circuit breaker is open: a cache miss returns
+	// the best cache match even below MinSimilarityContent, with Result.Degraded set, instead of failing.
+	FallbackToCache bool
 }
 
 // applyDefaults fills in default values for unset config fields
@@ -40,4 +133,8 @@ func (c *Config) applyDefaults() {
 	if c.MinSimilarityLabel == 0 {
 		c.MinSimilarityLabel = DefaultMinSimilarity
 	}
+
+	if c.LabelHintCount == 0 {
+		c.LabelHintCount = DefaultLabelHintCount
+	}
 }
@@ -0,0 +1,53 @@
+package classifier
+
+import (
+	"errors"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/retry"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+var (
+	// ErrClosed is returned by Classify once Close has been called
+	ErrClosed = errors.New("classifier is shutting down")
+
+	// ErrEmptyInput is returned when the text to classify is empty or whitespace
+	ErrEmptyInput = errors.New("cannot classify empty text")
+
+	// ErrEmptyLabel is returned when the LLM responds with an empty label
+	ErrEmptyLabel = errors.New("LLM returned empty label")
+
+	// ErrCorruptCacheEntry is returned when a cached vector is missing its label metadata
+	ErrCorruptCacheEntry = errors.New("cached vector missing label metadata")
+
+	// ErrUnsupportedVectorClient is returned when an operation needs a VectorClient that implements ExtendedVectorClient
+	ErrUnsupportedVectorClient = errors.New("vector client does not implement ExtendedVectorClient")
+
+	// ErrContextLength is matched by LLM errors for input exceeding the model's context length.
+	// Classify retries with truncated input before returning it.
+	ErrContextLength = types.ErrContextLength
+
+	// ErrRateLimited, ErrQuotaExceeded, ErrInvalidRequest, ErrContentFilter and ErrProviderServer
+	// are matched by provider errors of the corresponding category
+	ErrRateLimited    = types.ErrRateLimited
+	ErrQuotaExceeded  = types.ErrQuotaExceeded
+	ErrInvalidRequest = types.ErrInvalidRequest
+	ErrContentFilter  = types.ErrContentFilter
+	ErrProviderServer = types.ErrProviderServer
+
+	// ErrDimensionMismatch is matched by errors for embeddings whose dimension doesn't match the
+	// configured model dimensions or vector index
+	ErrDimensionMismatch = types.ErrDimensionMismatch
+
+	// ErrCircuitOpen is matched by errors from providers whose circuit breaker is rejecting calls
+	ErrCircuitOpen = retry.ErrCircuitOpen
+)
+
+// ProviderError wraps a failure returned by an external provider. Use errors.As to inspect it.
+type ProviderError = types.ProviderError
+
+// CircuitOpenError is returned when a provider call is rejected by an open circuit breaker
+type CircuitOpenError = retry.CircuitOpenError
+
+// RetryExhaustedError is returned when every retry attempt failed. It unwraps to the last attempt's error.
+type RetryExhaustedError = retry.RetryExhaustedError
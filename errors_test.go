@@ -0,0 +1,171 @@
+package classifier_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestClassifier_TypedErrors(t *testing.T) {
+	newClassifier := func(t *testing.T, cfg classifier.Config) *classifier.Classifier {
+		t.Helper()
+		if cfg.EmbeddingClient == nil {
+			cfg.EmbeddingClient = &testutil.MockEmbeddingClient{}
+		}
+		if cfg.VectorClientContent == nil {
+			cfg.VectorClientContent = testutil.NewMockVectorClient()
+		}
+		cfg.VectorClientLabel = testutil.NewMockVectorClient()
+		if cfg.LLMClient == nil {
+			cfg.LLMClient = &testutil.MockLLMClient{}
+		}
+		cfg.DSUPersistence = &testutil.MockDSUPersistence{}
+
+		clf, err := classifier.NewClassifier(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create classifier: %v", err)
+		}
+		return clf
+	}
+
+	t.Run("empty input", func(t *testing.T) {
+		clf := newClassifier(t, classifier.Config{})
+		if _, err := clf.Classify(context.Background(), "  "); !errors.Is(err, classifier.ErrEmptyInput) {
+			t.Errorf("Expected ErrEmptyInput, got %v", err)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		clf := newClassifier(t, classifier.Config{})
+		if err := clf.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if _, err := clf.Classify(context.Background(), "hello"); !errors.Is(err, classifier.ErrClosed) {
+			t.Errorf("Expected ErrClosed, got %v", err)
+		}
+	})
+
+	t.Run("empty label", func(t *testing.T) {
+		clf := newClassifier(t, classifier.Config{
+			LLMClient: &testutil.MockLLMClient{
+				ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+					return " ", nil
+				},
+			},
+		})
+		if _, err := clf.Classify(context.Background(), "hello"); !errors.Is(err, classifier.ErrEmptyLabel) {
+			t.Errorf("Expected ErrEmptyLabel, got %v", err)
+		}
+	})
+
+	t.Run("corrupt cache entry", func(t *testing.T) {
+		vectorContent := testutil.NewMockVectorClient()
+		vectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+			return []types.VectorMatch{{ID: "broken", Score: 0.99, Metadata: map[string]any{}}}, nil
+		}
+
+		clf := newClassifier(t, classifier.Config{VectorClientContent: vectorContent})
+		if _, err := clf.Classify(context.Background(), "hello"); !errors.Is(err, classifier.ErrCorruptCacheEntry) {
+			t.Errorf("Expected ErrCorruptCacheEntry, got %v", err)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		clf := newClassifier(t, classifier.Config{
+			EmbeddingClient: &testutil.MockEmbeddingClient{
+				GenerateEmbeddingFunc: func(ctx context.Context, text string) ([]float32, error) {
+					return nil, &types.ProviderError{Provider: types.ProviderVoyage, Op: "embed", StatusCode: 429, Retryable: true, Err: errors.New("rate limited")}
+				},
+			},
+		})
+
+		_, err := clf.Classify(context.Background(), "hello")
+		var providerErr *classifier.ProviderError
+		if !errors.As(err, &providerErr) {
+			t.Fatalf("Expected ProviderError, got %v", err)
+		}
+		if providerErr.Provider != types.ProviderVoyage || providerErr.StatusCode != 429 || !providerErr.Retryable {
+			t.Errorf("Unexpected ProviderError: %+v", providerErr)
+		}
+	})
+}
+
+func TestClassifier_FallbackToCache(t *testing.T) {
+	newClassifier := func(fallback bool) *classifier.Classifier {
+		vectorContent := testutil.NewMockVectorClient()
+		vectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+			return []types.VectorMatch{{ID: "near", Score: 0.6, Metadata: map[string]any{"label": "greeting"}}}, nil
+		}
+
+		clf, err := classifier.NewClassifier(classifier.Config{
+			EmbeddingClient:     &testutil.MockEmbeddingClient{},
+			VectorClientContent: vectorContent,
+			VectorClientLabel:   testutil.NewMockVectorClient(),
+			LLMClient: &testutil.MockLLMClient{
+				ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+					return "", &classifier.CircuitOpenError{Name: "OpenAI"}
+				},
+			},
+			DSUPersistence:  &testutil.MockDSUPersistence{},
+			FallbackToCache: fallback,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create classifier: %v", err)
+		}
+		return clf
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := newClassifier(false).Classify(context.Background(), "hi there")
+		if !errors.Is(err, classifier.ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		result, err := newClassifier(true).Classify(context.Background(), "hi there")
+		if err != nil {
+			t.Fatalf("Expected degraded result, got error: %v", err)
+		}
+		if result.Label != "greeting" || !result.Degraded || result.CacheHit || result.Confidence != 0.6 {
+			t.Errorf("Unexpected degraded result: %+v", result)
+		}
+	})
+}
+
+func TestClassifier_TruncatesOnContextLength(t *testing.T) {
+	var lengths []int
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+				lengths = append(lengths, len(text))
+				if len(text) > 25 {
+					return "", &types.ProviderError{Provider: types.ProviderOpenAI, Op: "chat", StatusCode: 400, Err: types.ErrContextLength}
+				}
+				return "long_text", nil
+			},
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	result, err := clf.Classify(context.Background(), "0123456789012345678901234567890123456789")
+	if err != nil {
+		t.Fatalf("Expected truncated input to be classified, got error: %v", err)
+	}
+	if result.Label != "long_text" {
+		t.Errorf("Expected label 'long_text', got '%s'", result.Label)
+	}
+	if len(lengths) != 2 || lengths[0] != 40 || lengths[1] != 20 {
+		t.Errorf("Expected input to be halved once, got lengths %v", lengths)
+	}
+}
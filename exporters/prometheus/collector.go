@@ -0,0 +1,157 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace is the metric namespace used when none is provided
+const DefaultNamespace = "consistent_classifier"
+
+// Collector records classifier instrumentation events as Prometheus metrics.
+// It implements both types.MetricsRecorder and prometheus.Collector.
+type Collector struct {
+	classifications *prom.CounterVec
+	stageLatency    *prom.HistogramVec
+	queueDepth      prom.Gauge
+	dsuLabels       prom.Gauge
+	dsuSets         prom.Gauge
+	retries         *prom.CounterVec
+	hedges          *prom.CounterVec
+	rateLimitWait   *prom.HistogramVec
+	tokens          *prom.CounterVec
+}
+
+var _ types.MetricsRecorder = (*Collector)(nil)
+var _ prom.Collector = (*Collector)(nil)
+
+// NewCollector creates a new collector. If namespace is empty, uses DefaultNamespace.
+func NewCollector(namespace string) *Collector {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	return &Collector{
+		classifications: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "classifications_total",
+			Help:      "Classify calls by outcome (hit, miss, error).",
+		}, []string{"outcome"}),
+		stageLatency: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stage_duration_seconds",
+			Help:      "Latency of classify pipeline stages (embed, search, llm, cluster, upsert).",
+			Buckets:   prom.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"stage"}),
+		queueDepth: prom.NewGauge(prom.GaugeOpts{
+			Namespace: namespace,
+			Name:      "background_queue_depth",
+			Help:      "Background tasks (clustering, vector upserts) in flight.",
+		}),
+		dsuLabels: prom.NewGauge(prom.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dsu_labels",
+			Help:      "Unique labels in the DSU.",
+		}),
+		dsuSets: prom.NewGauge(prom.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dsu_sets",
+			Help:      "Label clusters in the DSU after merging.",
+		}),
+		retries: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Retry attempts by API.",
+		}, []string{"api"}),
+		hedges: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "hedges_total",
+			Help:      "Hedged requests by API and which attempt returned first (primary, hedge).",
+		}, []string{"api", "winner"}),
+		rateLimitWait: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_wait_seconds",
+			Help:      "Time requests waited for the client-side rate limiter, by API.",
+			Buckets:   prom.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"api"}),
+		tokens: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_tokens_total",
+			Help:      "LLM tokens consumed by model and type (prompt, completion).",
+		}, []string{"model", "type"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.classifications.Describe(ch)
+	c.stageLatency.Describe(ch)
+	c.queueDepth.Describe(ch)
+	c.dsuLabels.Describe(ch)
+	c.dsuSets.Describe(ch)
+	c.retries.Describe(ch)
+	c.hedges.Describe(ch)
+	c.rateLimitWait.Describe(ch)
+	c.tokens.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.classifications.Collect(ch)
+	c.stageLatency.Collect(ch)
+	c.queueDepth.Collect(ch)
+	c.dsuLabels.Collect(ch)
+	c.dsuSets.Collect(ch)
+	c.retries.Collect(ch)
+	c.hedges.Collect(ch)
+	c.rateLimitWait.Collect(ch)
+	c.tokens.Collect(ch)
+}
+
+// RecordClassification implements types.MetricsRecorder
+func (c *Collector) RecordClassification(outcome string) {
+	c.classifications.WithLabelValues(outcome).Inc()
+}
+
+// RecordStageLatency implements types.MetricsRecorder
+func (c *Collector) RecordStageLatency(stage string, duration time.Duration) {
+	c.stageLatency.WithLabelValues(stage).Observe(duration.Seconds())
+}
+
+// RecordQueueDepth implements types.MetricsRecorder
+func (c *Collector) RecordQueueDepth(depth int) {
+	c.queueDepth.Set(float64(depth))
+}
+
+// RecordLabelSets implements types.MetricsRecorder
+func (c *Collector) RecordLabelSets(labels int, sets int) {
+	c.dsuLabels.Set(float64(labels))
+	c.dsuSets.Set(float64(sets))
+}
+
+// RecordRetry implements types.MetricsRecorder
+func (c *Collector) RecordRetry(api string) {
+	c.retries.WithLabelValues(api).Inc()
+}
+
+// RecordHedge implements types.MetricsRecorder
+func (c *Collector) RecordHedge(api string, won bool) {
+	winner := "primary"
+	if won {
+		winner = "hedge"
+	}
+	c.hedges.WithLabelValues(api, winner).Inc()
+}
+
+// RecordRateLimitWait implements types.MetricsRecorder
+func (c *Collector) RecordRateLimitWait(api string, wait time.Duration) {
+	c.rateLimitWait.WithLabelValues(api).Observe(wait.Seconds())
+}
+
+// RecordTokenUsage implements types.MetricsRecorder
+func (c *Collector) RecordTokenUsage(model string, promptTokens int, completionTokens int) {
+	c.tokens.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	c.tokens.WithLabelValues(model, "completion").Add(float64(completionTokens))
+}
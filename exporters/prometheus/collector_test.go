@@ -0,0 +1,130 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/exporters/prometheus"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	prom "github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector_Register(t *testing.T) {
+	registry := prom.NewRegistry()
+	collector := prometheus.NewCollector("")
+
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+
+	collector.RecordClassification(types.OutcomeHit)
+	collector.RecordStageLatency(types.StageEmbed, 20*time.Millisecond)
+	collector.RecordRetry("OpenAI chat")
+	collector.RecordHedge("Pinecone search", true)
+	collector.RecordRateLimitWait("OpenAI chat", 50*time.Millisecond)
+	collector.RecordTokenUsage("gpt-4.1-mini", 100, 5)
+
+	expected := `
+# HELP consistent_classifier_llm_tokens_total LLM tokens consumed by model and type (prompt, completion).
+# TYPE consistent_classifier_llm_tokens_total counter
+consistent_classifier_llm_tokens_total{model="gpt-4.1-mini",type="completion"} 5
+consistent_classifier_llm_tokens_total{model="gpt-4.1-mini",type="prompt"} 100
+`
+	if err := promtest.GatherAndCompare(registry, strings.NewReader(expected), "consistent_classifier_llm_tokens_total"); err != nil {
+		t.Error(err)
+	}
+
+	expected = `
+# HELP consistent_classifier_hedges_total Hedged requests by API and which attempt returned first (primary, hedge).
+# TYPE consistent_classifier_hedges_total counter
+consistent_classifier_hedges_total{api="Pinecone search",winner="hedge"} 1
+`
+	if err := promtest.GatherAndCompare(registry, strings.NewReader(expected), "consistent_classifier_hedges_total"); err != nil {
+		t.Error(err)
+	}
+
+	if count := promtest.CollectAndCount(collector, "consistent_classifier_stage_duration_seconds"); count != 1 {
+		t.Errorf("Expected 1 stage histogram series, got %d", count)
+	}
+	if count := promtest.CollectAndCount(collector, "consistent_classifier_rate_limit_wait_seconds"); count != 1 {
+		t.Errorf("Expected 1 rate limit wait histogram series, got %d", count)
+	}
+}
+
+func TestCollector_WithClassifier(t *testing.T) {
+	collector := prometheus.NewCollector("test")
+
+	hit := false
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		if hit {
+			return []types.VectorMatch{{ID: "id", Score: 0.99, Metadata: map[string]any{"label": "greeting"}}}, nil
+		}
+		return []types.VectorMatch{}, nil
+	}
+
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			if text == "fail" {
+				return "", errors.New("llm down")
+			}
+			return "greeting", nil
+		},
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           mockLLM,
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		MetricsRecorder:     collector,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := clf.Classify(ctx, "hello"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if _, err := clf.Classify(ctx, "fail"); err == nil {
+		t.Fatal("Expected error from failing LLM")
+	}
+	hit = true
+	if _, err := clf.Classify(ctx, "hello again"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	expected := `
+# HELP test_classifications_total Classify calls by outcome (hit, miss, error).
+# TYPE test_classifications_total counter
+test_classifications_total{outcome="error"} 1
+test_classifications_total{outcome="hit"} 1
+test_classifications_total{outcome="miss"} 1
+# HELP test_dsu_labels Unique labels in the DSU.
+# TYPE test_dsu_labels gauge
+test_dsu_labels 1
+# HELP test_dsu_sets Label clusters in the DSU after merging.
+# TYPE test_dsu_sets gauge
+test_dsu_sets 1
+# HELP test_background_queue_depth Background tasks (clustering, vector upserts) in flight.
+# TYPE test_background_queue_depth gauge
+test_background_queue_depth 0
+`
+	if err := promtest.CollectAndCompare(collector, strings.NewReader(expected),
+		"test_classifications_total", "test_dsu_labels", "test_dsu_sets", "test_background_queue_depth"); err != nil {
+		t.Error(err)
+	}
+
+	// embed, search, llm, cluster and upsert stages are all observed on a miss
+	if count := promtest.CollectAndCount(collector, "test_stage_duration_seconds"); count != 5 {
+		t.Errorf("Expected 5 stage histogram series, got %d", count)
+	}
+}
@@ -2,8 +2,10 @@ package classifier_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/FrenchMajesty/consistent-classifier"
@@ -246,3 +248,143 @@ func TestFileDSUPersistence_NewFileDSUPersistence(t *testing.T) {
 	// The constructor is simple, just verify it creates an instance
 	// Actual functionality is tested in other tests
 }
+
+func TestFileDSUPersistence_RoundTrip_Hierarchy(t *testing.T) {
+	filepath := filepath.Join(t.TempDir(), "hierarchy.bin")
+
+	originalDSU := disjoint_set.NewDSU()
+	originalDSU.Add("refund_request")
+	originalDSU.Add("money_back")
+	originalDSU.Union(0, 1)
+	originalDSU.SetParent("money_back", "billing")
+
+	persistence := classifier.NewFileDSUPersistence(filepath)
+	if err := persistence.Save(originalDSU); err != nil {
+		t.Fatalf("Failed to save DSU: %v", err)
+	}
+
+	loadedDSU, err := persistence.Load()
+	if err != nil {
+		t.Fatalf("Failed to load DSU: %v", err)
+	}
+
+	path := loadedDSU.Path("money_back")
+	if len(path) != 2 || path[0] != "billing" || path[1] != "refund_request" {
+		t.Errorf("Expected path [billing refund_request], got %v", path)
+	}
+
+	// The reverse index must be rebuilt so root labels resolve after loading
+	if label := loadedDSU.FindLabel(loadedDSU.FindOrCreate("money_back")); label != "refund_request" {
+		t.Errorf("Expected root label 'refund_request', got '%s'", label)
+	}
+}
+
+func TestFileDSUPersistence_RoundTrip_Usage(t *testing.T) {
+	filepath := filepath.Join(t.TempDir(), "usage.bin")
+
+	originalDSU := disjoint_set.NewDSU()
+	originalDSU.Observe("refund_request")
+	originalDSU.Observe("money_back")
+	originalDSU.Observe("money_back")
+	originalDSU.Union(originalDSU.FindOrCreate("refund_request"), originalDSU.FindOrCreate("money_back"))
+	originalDSU.SetCanonical("refund_request", "money_back")
+
+	persistence := classifier.NewFileDSUPersistence(filepath)
+	if err := persistence.Save(originalDSU); err != nil {
+		t.Fatalf("Failed to save DSU: %v", err)
+	}
+
+	loadedDSU, err := persistence.Load()
+	if err != nil {
+		t.Fatalf("Failed to load DSU: %v", err)
+	}
+
+	original, _ := originalDSU.Usage("money_back")
+	loaded, ok := loadedDSU.Usage("money_back")
+	if !ok || loaded.Count != 2 || !loaded.FirstSeen.Equal(original.FirstSeen) {
+		t.Errorf("Expected usage %+v, got %+v", original, loaded)
+	}
+	if canonical := loadedDSU.Canonical("refund_request"); canonical != "money_back" {
+		t.Errorf("Expected canonical label 'money_back', got '%s'", canonical)
+	}
+}
+
+func TestFileDSUPersistence_RoundTrip_RemovedLabels(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compact=%v", compact), func(t *testing.T) {
+			filepath := filepath.Join(t.TempDir(), "removed.bin")
+
+			originalDSU := disjoint_set.NewDSU()
+			originalDSU.Union(originalDSU.FindOrCreate("billing"), originalDSU.FindOrCreate("invoice"))
+			originalDSU.Union(originalDSU.FindOrCreate("billing"), originalDSU.FindOrCreate("payment"))
+			originalDSU.FindOrCreate("obsolete")
+			originalDSU.SetParent("billing", "finance")
+			originalDSU.Remove("billing")
+			originalDSU.Remove("obsolete")
+			if compact {
+				originalDSU.Compact()
+			}
+
+			persistence := classifier.NewFileDSUPersistence(filepath)
+			if err := persistence.Save(originalDSU); err != nil {
+				t.Fatalf("Failed to save DSU: %v", err)
+			}
+			loadedDSU, err := persistence.Load()
+			if err != nil {
+				t.Fatalf("Failed to load DSU: %v", err)
+			}
+
+			if loadedDSU.Size() != 2 || loadedDSU.CountSets() != 1 {
+				t.Errorf("Expected 2 labels in 1 set, got %d in %d", loadedDSU.Size(), loadedDSU.CountSets())
+			}
+			if loadedDSU.Contains("billing") || loadedDSU.Contains("obsolete") {
+				t.Error("Expected removed labels to stay removed")
+			}
+			if !reflect.DeepEqual(loadedDSU.Sets(), originalDSU.Sets()) {
+				t.Errorf("Expected sets %v, got %v", originalDSU.Sets(), loadedDSU.Sets())
+			}
+			if parent := loadedDSU.Parent("payment"); parent != "finance" {
+				t.Errorf("Expected parent 'finance', got '%s'", parent)
+			}
+
+			// New labels must not collide with the slots of removed ones
+			loadedDSU.FindOrCreate("refund")
+			if loadedDSU.Size() != 3 || loadedDSU.CountSets() != 2 {
+				t.Errorf("Expected 3 labels in 2 sets, got %d in %d", loadedDSU.Size(), loadedDSU.CountSets())
+			}
+		})
+	}
+}
+
+func TestFileDSUPersistence_RoundTrip_Stats(t *testing.T) {
+	filepath := filepath.Join(t.TempDir(), "stats.bin")
+
+	originalDSU := disjoint_set.NewDSU()
+	originalDSU.Observe("money_back")
+	originalDSU.SetOrigin("money_back", disjoint_set.OriginLLM)
+	originalDSU.SetOrigin("refund_request", disjoint_set.OriginImport)
+	originalDSU.MergeLabels("money_back", "refund_request", 0.87)
+
+	persistence := classifier.NewFileDSUPersistence(filepath)
+	if err := persistence.Save(originalDSU); err != nil {
+		t.Fatalf("Failed to save DSU: %v", err)
+	}
+
+	loadedDSU, err := persistence.Load()
+	if err != nil {
+		t.Fatalf("Failed to load DSU: %v", err)
+	}
+
+	for _, label := range []string{"money_back", "refund_request"} {
+		original, _ := originalDSU.Stats(label)
+		loaded, ok := loadedDSU.Stats(label)
+		if !ok || loaded.Origin != original.Origin || !loaded.LastSeen.Equal(original.LastSeen) || len(loaded.Merges) != len(original.Merges) {
+			t.Errorf("Expected stats %+v, got %+v", original, loaded)
+		}
+		for i, merge := range loaded.Merges {
+			if merge.Into != original.Merges[i].Into || merge.Score != original.Merges[i].Score || !merge.At.Equal(original.Merges[i].At) {
+				t.Errorf("Expected merge %+v, got %+v", original.Merges[i], merge)
+			}
+		}
+	}
+}
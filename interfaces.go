@@ -12,17 +12,71 @@ type EmbeddingClient interface {
 	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
 }
 
+// InputTypeEmbeddingClient is an optional extension of EmbeddingClient that embeds text for a specific
+// use. The classifier embeds cache lookups as queries and cache writes as documents.
+type InputTypeEmbeddingClient interface {
+	EmbeddingClient
+	GenerateEmbeddingForInput(ctx context.Context, text string, inputType types.EmbeddingInputType) ([]float32, error)
+}
+
+// BatchEmbeddingClient is an optional extension of EmbeddingClient that embeds several texts in one
+// request. BatchingEmbeddingClient uses it to send batched calls.
+type BatchEmbeddingClient interface {
+	EmbeddingClient
+	GenerateEmbeddings(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error)
+}
+
 // VectorClient performs vector similarity search and storage operations
 type VectorClient interface {
 	Search(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error)
 	Upsert(ctx context.Context, id string, vector []float32, metadata map[string]any) error
 }
 
+// ExtendedVectorClient is an optional extension of VectorClient for managing stored vectors.
+// The classifier uses it to filter cache searches by tenant.
+type ExtendedVectorClient interface {
+	VectorClient
+
+	// SearchWithFilter is Search restricted to vectors whose metadata matches filter. Filters use
+	// Pinecone's metadata filter syntax, e.g. {"tenant": {"$eq": "acme"}}.
+	SearchWithFilter(ctx context.Context, vector []float32, topK int, filter map[string]any) ([]types.VectorMatch, error)
+
+	// Fetch returns the stored vectors with the given IDs, keyed by ID. Unknown IDs are absent from the result.
+	Fetch(ctx context.Context, ids []string) (map[string]types.VectorRecord, error)
+
+	// UpdateMetadata sets the given metadata fields on a stored vector, leaving other fields unchanged
+	UpdateMetadata(ctx context.Context, id string, metadata map[string]any) error
+
+	// Delete removes the vectors with the given IDs. Unknown IDs are ignored.
+	Delete(ctx context.Context, ids []string) error
+
+	// BatchUpsert stores several vectors at once
+	BatchUpsert(ctx context.Context, records []types.VectorRecord) error
+}
+
 // LLMClient classifies text into category labels
 type LLMClient interface {
 	Classify(ctx context.Context, text string) (string, error)
 }
 
+// PromptedLLMClient is an optional extension of LLMClient that accepts retrieved context
+// (known labels and few-shot examples) to render into the prompt
+type PromptedLLMClient interface {
+	LLMClient
+	ClassifyWithPrompt(ctx context.Context, data types.PromptData) (string, error)
+}
+
+// ParentProposer proposes a coarse parent category for a label cluster (e.g. "billing" for "refund_request")
+type ParentProposer interface {
+	ProposeParent(ctx context.Context, label string, categories []string) (string, error)
+}
+
+// ClusterNamer names a label cluster by summarising its labels (e.g. "refund_request" for
+// "refund", "money_back" and "chargeback_request"). Used by SummarizedLabel.
+type ClusterNamer interface {
+	NameCluster(ctx context.Context, labels []string) (string, error)
+}
+
 // DisjointSetPersistence handles loading and saving the Disjoint Set Union structure
 type DisjointSetPersistence interface {
 	Load() (*disjoint_set.DSU, error)
@@ -0,0 +1,197 @@
+package clustering
+
+import (
+	"math"
+)
+
+// Linkage selects how the similarity between two clusters is computed from their members
+type Linkage int
+
+const (
+	// LinkageAverage uses the mean similarity over all pairs of members
+	LinkageAverage Linkage = iota
+
+	// LinkageComplete uses the least similar pair of members, keeping clusters tight
+	LinkageComplete
+)
+
+// Agglomerative groups vectors by hierarchical agglomerative clustering over cosine similarity,
+// merging clusters while their linkage similarity is at least threshold. It returns the clusters as
+// lists of vector indices, in ascending order of their first index. Memory is quadratic in len(vectors).
+func Agglomerative(vectors [][]float32, threshold float32, linkage Linkage) [][]int {
+	n := len(vectors)
+	if n == 0 {
+		return nil
+	}
+
+	sim := similarityMatrix(vectors)
+	merges := nearestNeighborChain(sim, n, linkage)
+
+	// Linkages are reducible, so the merges at or above threshold are exactly those below
+	// the threshold cut of the dendrogram
+	groups := newUnionFind(n)
+	for _, m := range merges {
+		if m.similarity >= threshold {
+			groups.union(m.a, m.b)
+		}
+	}
+
+	clusters := make([][]int, 0)
+	clusterOf := make(map[int]int)
+	for i := range n {
+		root := groups.find(i)
+		idx, ok := clusterOf[root]
+		if !ok {
+			idx = len(clusters)
+			clusterOf[root] = idx
+			clusters = append(clusters, nil)
+		}
+		clusters[idx] = append(clusters[idx], i)
+	}
+	return clusters
+}
+
+// Similarity returns the linkage similarity between the groups of vectors a and b. Empty groups have similarity 0.
+func (linkage Linkage) Similarity(a, b [][]float32) float32 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	var sum float32
+	least := float32(math.Inf(1))
+	for _, u := range a {
+		for _, v := range b {
+			s := CosineSimilarity(u, v)
+			sum += s
+			least = min(least, s)
+		}
+	}
+	if linkage == LinkageComplete {
+		return least
+	}
+	return sum / float32(len(a)*len(b))
+}
+
+// merge joins the clusters represented by vectors a and b
+type merge struct {
+	a, b       int
+	similarity float32
+}
+
+// nearestNeighborChain builds the full dendrogram in O(n²) time. sim is the n×n similarity matrix,
+// updated in place as clusters merge; a merged cluster is represented by one of its members' rows.
+func nearestNeighborChain(sim []float32, n int, linkage Linkage) []merge {
+	active := make([]bool, n)
+	size := make([]int, n)
+	for i := range n {
+		active[i] = true
+		size[i] = 1
+	}
+
+	merges := make([]merge, 0, n-1)
+	chain := make([]int, 0, n)
+	next := 0 // Lowest index that may still be active, to start new chains
+	for remaining := n; remaining > 1; {
+		if len(chain) == 0 {
+			for !active[next] {
+				next++
+			}
+			chain = append(chain, next)
+		}
+
+		a := chain[len(chain)-1]
+		prev := -1
+		best, bestSim := -1, float32(math.Inf(-1))
+		if len(chain) > 1 {
+			// Preferring the previous chain element on ties guarantees the chain terminates
+			prev = chain[len(chain)-2]
+			best, bestSim = prev, sim[a*n+prev]
+		}
+		for k := range n {
+			if k != a && active[k] && sim[a*n+k] > bestSim {
+				best, bestSim = k, sim[a*n+k]
+			}
+		}
+
+		if best != prev {
+			chain = append(chain, best)
+			continue
+		}
+
+		// a and prev are reciprocal nearest neighbours: merge prev into a
+		chain = chain[:len(chain)-2]
+		merges = append(merges, merge{a: a, b: prev, similarity: bestSim})
+		for k := range n {
+			if k == a || k == prev || !active[k] {
+				continue
+			}
+			var s float32
+			switch linkage {
+			case LinkageComplete:
+				s = min(sim[a*n+k], sim[prev*n+k])
+			default:
+				s = (float32(size[a])*sim[a*n+k] + float32(size[prev])*sim[prev*n+k]) / float32(size[a]+size[prev])
+			}
+			sim[a*n+k] = s
+			sim[k*n+a] = s
+		}
+		size[a] += size[prev]
+		active[prev] = false
+		remaining--
+	}
+
+	return merges
+}
+
+// similarityMatrix returns the pairwise cosine similarities of vectors as a flat n×n matrix
+func similarityMatrix(vectors [][]float32) []float32 {
+	n := len(vectors)
+	sim := make([]float32, n*n)
+	for i := range n {
+		sim[i*n+i] = 1
+		for j := i + 1; j < n; j++ {
+			s := CosineSimilarity(vectors[i], vectors[j])
+			sim[i*n+j] = s
+			sim[j*n+i] = s
+		}
+	}
+	return sim
+}
+
+// CosineSimilarity returns the cosine similarity of a and b, over their common length.
+// Zero vectors have similarity 0 with everything.
+func CosineSimilarity(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// unionFind groups vector indices for the threshold cut
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+func (u unionFind) find(x int) int {
+	for u[x] != x {
+		u[x] = u[u[x]]
+		x = u[x]
+	}
+	return x
+}
+
+func (u unionFind) union(x, y int) {
+	u[u.find(x)] = u.find(y)
+}
@@ -0,0 +1,73 @@
+package clustering
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// unit returns the 2D unit vector at the given angle in degrees
+func unit(degrees float64) []float32 {
+	radians := degrees * math.Pi / 180
+	return []float32{float32(math.Cos(radians)), float32(math.Sin(radians))}
+}
+
+func TestAgglomerative_Groups(t *testing.T) {
+	vectors := [][]float32{unit(0), unit(90), unit(2), unit(91), unit(45)}
+
+	// cos(5°) ≈ 0.996 and cos(45°) ≈ 0.707: only the near-identical pairs merge
+	clusters := Agglomerative(vectors, 0.99, LinkageAverage)
+	expected := [][]int{{0, 2}, {1, 3}, {4}}
+	if !reflect.DeepEqual(clusters, expected) {
+		t.Errorf("Expected %v, got %v", expected, clusters)
+	}
+}
+
+func TestAgglomerative_Linkage(t *testing.T) {
+	// A chain of vectors 20° apart: neighbours are similar (cos 20° ≈ 0.94), the ends are not (cos 60° = 0.5)
+	vectors := [][]float32{unit(0), unit(20), unit(40), unit(60)}
+
+	complete := Agglomerative(vectors, 0.9, LinkageComplete)
+	if len(complete) != 2 {
+		t.Errorf("Expected complete linkage to stop the chain at 2 clusters, got %v", complete)
+	}
+
+	average := Agglomerative(vectors, 0.5, LinkageAverage)
+	if len(average) != 1 {
+		t.Errorf("Expected a low threshold to merge everything, got %v", average)
+	}
+
+	separate := Agglomerative(vectors, 0.99, LinkageAverage)
+	if len(separate) != 4 {
+		t.Errorf("Expected a high threshold to merge nothing, got %v", separate)
+	}
+}
+
+func TestLinkage_Similarity(t *testing.T) {
+	label := [][]float32{unit(0)}
+	cluster := [][]float32{unit(0), unit(60)}
+
+	// cos(0°) = 1 and cos(60°) = 0.5
+	if s := LinkageAverage.Similarity(label, cluster); math.Abs(float64(s)-0.75) > 1e-6 {
+		t.Errorf("Expected average linkage 0.75, got %v", s)
+	}
+	if s := LinkageComplete.Similarity(label, cluster); math.Abs(float64(s)-0.5) > 1e-6 {
+		t.Errorf("Expected complete linkage 0.5, got %v", s)
+	}
+	if s := LinkageAverage.Similarity(label, nil); s != 0 {
+		t.Errorf("Expected an empty group to have similarity 0, got %v", s)
+	}
+}
+
+func TestAgglomerative_Edges(t *testing.T) {
+	if clusters := Agglomerative(nil, 0.8, LinkageAverage); clusters != nil {
+		t.Errorf("Expected no clusters, got %v", clusters)
+	}
+
+	// Zero vectors never merge with anything
+	clusters := Agglomerative([][]float32{{0, 0}, {1, 0}, {1, 0}}, 0.8, LinkageAverage)
+	expected := [][]int{{0}, {1, 2}}
+	if !reflect.DeepEqual(clusters, expected) {
+		t.Errorf("Expected %v, got %v", expected, clusters)
+	}
+}
@@ -1,15 +1,30 @@
 package disjoint_set
 
 import (
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 )
 
-// DSU represents a Disjoint Set Union data structure
+// DSU represents a Disjoint Set Union data structure. It is safe for concurrent use: methods that
+// change the sets take the write lock and compress paths, while read-only methods share the read
+// lock and walk to the root without compressing. Union by rank keeps those walks logarithmic.
 type DSU struct {
 	root       []int
 	rank       []int
+	size       []int       // Number of labels in each set, only meaningful at roots
+	parent     []string    // Parent category of each set, only meaningful at roots
+	canonical  []string    // Canonical label of each set, only meaningful at roots. Empty means the root label.
+	count      []int       // Times each label was observed
+	firstSeen  []time.Time // When each label was added
+	lastSeen   []time.Time // When each label was last observed
+	origin     []Origin    // How each label entered the DSU
+	merges     [][]Merge   // Merges of each label into other sets, oldest first
 	labels     map[string]int
-	labelIndex map[int]string
+	labelIndex map[int]string   // Removed labels' slots have no entry until Compact
+	changed    map[int]struct{} // Roots absorbed by Union since the last TakeChanged
 	lock       sync.RWMutex
 }
 
@@ -18,8 +33,17 @@ func NewDSU() *DSU {
 	return &DSU{
 		root:       make([]int, 0),
 		rank:       make([]int, 0),
+		size:       make([]int, 0),
+		parent:     make([]string, 0),
+		canonical:  make([]string, 0),
+		count:      make([]int, 0),
+		firstSeen:  make([]time.Time, 0),
+		lastSeen:   make([]time.Time, 0),
+		origin:     make([]Origin, 0),
+		merges:     make([][]Merge, 0),
 		labels:     make(map[string]int),
 		labelIndex: make(map[int]string),
+		changed:    make(map[int]struct{}),
 		lock:       sync.RWMutex{},
 	}
 }
@@ -36,12 +60,20 @@ func (d *DSU) Add(label string) int {
 func (d *DSU) add(label string) int {
 	d.root = append(d.root, len(d.root))
 	d.rank = append(d.rank, 0)
+	d.size = append(d.size, 1)
+	d.parent = append(d.parent, "")
+	d.canonical = append(d.canonical, "")
+	d.count = append(d.count, 0)
+	d.firstSeen = append(d.firstSeen, time.Now())
+	d.lastSeen = append(d.lastSeen, time.Time{})
+	d.origin = append(d.origin, OriginUnknown)
+	d.merges = append(d.merges, nil)
 	d.labels[label] = len(d.root) - 1
 	d.labelIndex[len(d.root)-1] = label
 	return d.labels[label]
 }
 
-// find finds the root of the set (internal, unlocked - caller must hold lock)
+// find finds the root of the set (internal, unlocked - caller must hold write lock)
 func (d *DSU) find(x int) int {
 	if d.root[x] == x {
 		return x
@@ -51,6 +83,14 @@ func (d *DSU) find(x int) int {
 	return d.root[x]
 }
 
+// findRoot finds the root of the set without path compression (internal, unlocked - caller must hold read lock)
+func (d *DSU) findRoot(x int) int {
+	for d.root[x] != x {
+		x = d.root[x]
+	}
+	return x
+}
+
 // FindOrCreate finds the root of the set by label, or adds it if it doesn't exist
 func (d *DSU) FindOrCreate(label string) int {
 	d.lock.Lock()
@@ -64,11 +104,29 @@ func (d *DSU) FindOrCreate(label string) int {
 	return d.find(idx)
 }
 
+// FindOrCreateRoot returns the root label of the label's set, adding the label if it doesn't exist.
+// Unlike FindOrCreate, the result stays valid across Compact.
+func (d *DSU) FindOrCreateRoot(label string) string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		idx = d.add(label)
+	}
+	return d.labelIndex[d.find(idx)]
+}
+
 // Union merges two sets
 func (d *DSU) Union(x int, y int) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
+	d.union(x, y)
+}
+
+// union merges two sets (internal, unlocked - caller must hold write lock)
+func (d *DSU) union(x int, y int) {
 	rootX := d.find(x)
 	rootY := d.find(y)
 
@@ -76,14 +134,30 @@ func (d *DSU) Union(x int, y int) {
 		return
 	}
 
+	newRoot, oldRoot := rootX, rootY
 	if d.rank[rootX] > d.rank[rootY] {
 		d.root[rootY] = rootX
 	} else if d.rank[rootX] < d.rank[rootY] {
 		d.root[rootX] = rootY
+		newRoot, oldRoot = rootY, rootX
 	} else {
 		d.root[rootY] = rootX
 		d.rank[rootX]++
 	}
+	d.size[newRoot] += d.size[oldRoot]
+
+	// Keep the absorbed set's parent category if the surviving root has none
+	if d.parent[newRoot] == "" {
+		d.parent[newRoot] = d.parent[oldRoot]
+	}
+	d.parent[oldRoot] = ""
+
+	// Likewise the canonical label, until it is chosen again for the merged set
+	if d.canonical[newRoot] == "" {
+		d.canonical[newRoot] = d.canonical[oldRoot]
+	}
+	d.canonical[oldRoot] = ""
+	d.changed[oldRoot] = struct{}{}
 }
 
 // Connected checks if two elements are in the same set
@@ -91,7 +165,7 @@ func (d *DSU) Connected(x int, y int) bool {
 	d.lock.RLock()
 	defer d.lock.RUnlock()
 
-	return d.find(x) == d.find(y)
+	return d.findRoot(x) == d.findRoot(y)
 }
 
 // Size returns the number of elements in the DSU
@@ -114,6 +188,30 @@ func (d *DSU) Labels() []string {
 	return labels
 }
 
+// Contains reports whether the label exists in the DSU
+func (d *DSU) Contains(label string) bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	_, ok := d.labels[label]
+	return ok
+}
+
+// Roots returns the labels of all set roots, sorted alphabetically
+func (d *DSU) Roots() []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	roots := make([]string, 0)
+	for i := range d.root {
+		if d.root[i] == i && !d.removed(i) {
+			roots = append(roots, d.labelIndex[i])
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
 // CountSets returns the number of unique sets in the DSU
 func (d *DSU) CountSets() int {
 	d.lock.RLock()
@@ -121,13 +219,143 @@ func (d *DSU) CountSets() int {
 
 	rootSet := make(map[int]bool)
 	for i := range d.root {
-		root := d.find(i)
+		if d.removed(i) {
+			continue
+		}
+		root := d.findRoot(i)
 		rootSet[root] = true
 	}
 
 	return len(rootSet)
 }
 
+// SetSize returns the number of labels in the set containing label, or 0 if the label doesn't exist
+func (d *DSU) SetSize(label string) int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return 0
+	}
+	return d.size[d.findRoot(idx)]
+}
+
+// Root returns the root label of the set containing label, and false if the label doesn't exist
+func (d *DSU) Root(label string) (string, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return "", false
+	}
+	return d.labelIndex[d.findRoot(idx)], true
+}
+
+// TakeChanged returns the labels whose root may have changed since the last call, sorted
+// alphabetically, and resets the tracking. These are the members of every set that absorbed
+// another set through Union.
+func (d *DSU) TakeChanged() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.changed) == 0 {
+		return nil
+	}
+
+	changedRoots := make(map[int]bool, len(d.changed))
+	for idx := range d.changed {
+		changedRoots[d.find(idx)] = true
+	}
+	clear(d.changed)
+
+	labels := make([]string, 0)
+	for i := range d.root {
+		if changedRoots[d.find(i)] && !d.removed(i) {
+			labels = append(labels, d.labelIndex[i])
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// MarkChanged flags the labels' sets to be returned by the next TakeChanged. Unknown labels are ignored.
+func (d *DSU) MarkChanged(labels ...string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, label := range labels {
+		if idx, ok := d.labels[label]; ok {
+			d.changed[idx] = struct{}{}
+		}
+	}
+}
+
+// Replace overwrites the DSU with a copy of other's sets and parents. Change tracking is reset.
+// other is copied before the DSU is locked, so the two are never locked together.
+func (d *DSU) Replace(other *DSU) {
+	d.ReplaceMerged(other, nil)
+}
+
+// ReplaceMerged is like Replace, but first calls merge with a snapshot of the DSU and the copy of other
+// about to replace it, so changes made since other was built can be carried over to next. merge runs
+// while the DSU is locked for writing, so no concurrent change is lost between the snapshot and the
+// swap. It must not call methods on the DSU itself. Sets flagged in next are returned by TakeChanged.
+// A nil merge replaces the DSU as Replace does.
+func (d *DSU) ReplaceMerged(other *DSU, merge func(current *DSU, next *DSU)) {
+	if other == d {
+		return
+	}
+	next := other.Clone()
+	clear(next.changed)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if merge != nil {
+		merge(d.snapshot(), next)
+	}
+	d.assign(next)
+}
+
+// Clone returns a copy of the DSU that shares no state with it
+func (d *DSU) Clone() *DSU {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.snapshot()
+}
+
+// snapshot returns a copy of the DSU (internal, unlocked - caller must hold lock)
+func (d *DSU) snapshot() *DSU {
+	merges := make([][]Merge, len(d.merges))
+	for i, m := range d.merges {
+		merges[i] = slices.Clone(m)
+	}
+	return &DSU{
+		root:       slices.Clone(d.root),
+		rank:       slices.Clone(d.rank),
+		size:       slices.Clone(d.size),
+		parent:     slices.Clone(d.parent),
+		canonical:  slices.Clone(d.canonical),
+		count:      slices.Clone(d.count),
+		firstSeen:  slices.Clone(d.firstSeen),
+		lastSeen:   slices.Clone(d.lastSeen),
+		origin:     slices.Clone(d.origin),
+		merges:     merges,
+		labels:     maps.Clone(d.labels),
+		labelIndex: maps.Clone(d.labelIndex),
+		changed:    maps.Clone(d.changed),
+	}
+}
+
+// assign takes over the state of next, which must not be shared (internal, unlocked - caller must hold lock)
+func (d *DSU) assign(next *DSU) {
+	d.root, d.rank, d.size, d.parent, d.canonical = next.root, next.rank, next.size, next.parent, next.canonical
+	d.count, d.firstSeen, d.lastSeen = next.count, next.firstSeen, next.lastSeen
+	d.origin, d.merges = next.origin, next.merges
+	d.labels, d.labelIndex, d.changed = next.labels, next.labelIndex, next.changed
+}
+
 // FindLabel finds the label by a root index or empty string if not found
 func (d *DSU) FindLabel(idx int) string {
 	d.lock.RLock()
@@ -0,0 +1,338 @@
+package disjoint_set
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDSU_ReadsDoNotCompressPaths(t *testing.T) {
+	d := NewDSU()
+	for _, pair := range [][2]string{{"a", "b"}, {"c", "d"}, {"a", "c"}} {
+		d.Union(d.FindOrCreate(pair[0]), d.FindOrCreate(pair[1]))
+	}
+	before := slices.Clone(d.root)
+
+	d.Connected(d.labels["d"], d.labels["a"])
+	d.CountSets()
+	d.Root("d")
+	d.Parent("d")
+	d.Path("d")
+	d.Categories()
+	d.Sets()
+	d.Members("d")
+	d.Canonical("d")
+
+	if !slices.Equal(d.root, before) {
+		t.Errorf("Expected reads to leave the forest unchanged, got %v, want %v", d.root, before)
+	}
+	if root, _ := d.Root("d"); root != "a" {
+		t.Errorf("Expected d to be rooted at a, got %q", root)
+	}
+}
+
+// TestDSU_ConcurrentAccess is meant to be run with -race
+func TestDSU_ConcurrentAccess(t *testing.T) {
+	const (
+		workers = 16
+		labels  = 200
+	)
+
+	d := NewDSU()
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range labels {
+				label := fmt.Sprintf("label-%d", (i*workers+w)%labels)
+				idx := d.FindOrCreate(label)
+
+				// Interleave merges with every kind of read
+				d.Union(d.FindOrCreate(fmt.Sprintf("label-%d", i%10)), idx)
+
+				switch i % 4 {
+				case 0:
+					d.CountSets()
+				case 1:
+					d.Connected(idx, d.FindOrCreate("label-0"))
+				case 2:
+					d.Members(label)
+					d.Root(label)
+				case 3:
+					d.Observe(label)
+					d.Sets()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if d.Size() != labels {
+		t.Errorf("Expected %d labels, got %d", labels, d.Size())
+	}
+	// Every label joined one of the first 10 labels
+	if sets := d.CountSets(); sets > 10 {
+		t.Errorf("Expected at most 10 sets, got %d", sets)
+	}
+
+	// The same unions applied sequentially give the same partition, whatever the interleaving
+	replay := NewDSU()
+	for w := range workers {
+		for i := range labels {
+			replay.Union(replay.FindOrCreate(fmt.Sprintf("label-%d", i%10)), replay.FindOrCreate(fmt.Sprintf("label-%d", (i*workers+w)%labels)))
+		}
+	}
+	for i := range labels {
+		label := fmt.Sprintf("label-%d", i)
+		root, _ := d.Root(label)
+		for _, member := range d.Members(root) {
+			if r, _ := d.Root(member.Label); r != root {
+				t.Fatalf("Member %s of %s reports root %s", member.Label, root, r)
+			}
+		}
+		if got, want := memberLabels(d, label), memberLabels(replay, label); !slices.Equal(got, want) {
+			t.Fatalf("Expected %s to be in a set of %v, got %v", label, want, got)
+		}
+	}
+}
+
+// memberLabels returns the sorted labels in the set of the given label
+func memberLabels(d *DSU, label string) []string {
+	labels := make([]string, 0)
+	for _, member := range d.Members(label) {
+		labels = append(labels, member.Label)
+	}
+	slices.Sort(labels)
+	return labels
+}
+
+func TestDSU_Replace(t *testing.T) {
+	a := NewDSU()
+	a.Union(a.FindOrCreate("billing"), a.FindOrCreate("invoice"))
+	b := NewDSU()
+	b.FindOrCreate("refund")
+
+	// Replacing a DSU with itself leaves it as is
+	a.Replace(a)
+	if got := memberLabels(a, "billing"); !slices.Equal(got, []string{"billing", "invoice"}) {
+		t.Errorf("Expected billing and invoice to stay together, got %v", got)
+	}
+
+	// Opposite replacements don't deadlock, since the two DSUs are never locked together
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for range 100 {
+			wg.Add(2)
+			go func() { defer wg.Done(); a.Replace(b) }()
+			go func() { defer wg.Done(); b.Replace(a) }()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected concurrent replacements to finish")
+	}
+
+	// The copy doesn't share state with the original
+	a = NewDSU()
+	a.Union(a.FindOrCreate("billing"), a.FindOrCreate("invoice"))
+	b.Replace(a)
+	b.FindOrCreate("extra")
+	if a.Contains("extra") {
+		t.Error("Expected a replaced DSU not to share state with its source")
+	}
+
+	// A merge carries the current state over to the replacement, whose flags survive the swap
+	a.FindOrCreate("late")
+	b.ReplaceMerged(a, func(current *DSU, next *DSU) {
+		if !current.Contains("extra") || next.Contains("extra") {
+			t.Error("Expected merge to see the current DSU and the copy replacing it")
+		}
+		next.Union(next.FindOrCreate("billing"), next.FindOrCreate("extra"))
+	})
+	if got := memberLabels(b, "extra"); !slices.Equal(got, []string{"billing", "extra", "invoice"}) {
+		t.Errorf("Expected the merged label in the billing set, got %v", got)
+	}
+	if changed := b.TakeChanged(); !slices.Contains(changed, "extra") {
+		t.Errorf("Expected the merged set to be flagged, got %v", changed)
+	}
+}
+
+func TestDSU_Remove(t *testing.T) {
+	d := NewDSU()
+	d.Union(d.FindOrCreate("billing"), d.FindOrCreate("invoice"))
+	d.Union(d.FindOrCreate("billing"), d.FindOrCreate("payment"))
+	d.FindOrCreate("refund")
+	d.SetParent("billing", "finance")
+	d.TakeChanged()
+
+	if _, ok := d.Remove("unknown"); ok {
+		t.Error("Expected removing an unknown label to report false")
+	}
+
+	// Removing a member leaves the root alone
+	removal, ok := d.Remove("payment")
+	if !ok {
+		t.Fatal("Expected payment to be removed")
+	}
+	if !reflect.DeepEqual(removal, Removal{Root: "billing", Remaining: []string{"billing", "invoice"}}) {
+		t.Errorf("Expected billing and invoice to remain under billing, got %+v", removal)
+	}
+	if root, _ := d.Root("invoice"); root != "billing" {
+		t.Errorf("Expected invoice to stay rooted at billing, got %q", root)
+	}
+
+	// Removing the root promotes the member added first
+	d.FindOrCreate("charge")
+	d.Union(d.FindOrCreate("billing"), d.FindOrCreate("charge"))
+	d.TakeChanged()
+	removal, ok = d.Remove("billing")
+	if !ok {
+		t.Fatal("Expected billing to be removed")
+	}
+	if !reflect.DeepEqual(removal, Removal{Root: "invoice", Rerooted: true, Remaining: []string{"invoice", "charge"}}) {
+		t.Errorf("Expected invoice and charge to remain under invoice, got %+v", removal)
+	}
+	for _, label := range []string{"invoice", "charge"} {
+		if root, _ := d.Root(label); root != "invoice" {
+			t.Errorf("Expected %s to be rooted at invoice, got %q", label, root)
+		}
+	}
+	if parent := d.Parent("charge"); parent != "finance" {
+		t.Errorf("Expected the new root to keep parent finance, got %q", parent)
+	}
+	if changed := d.TakeChanged(); !slices.Equal(changed, []string{"charge", "invoice"}) {
+		t.Errorf("Expected the re-rooted set to be flagged, got %v", changed)
+	}
+
+	if d.Size() != 3 || d.CountSets() != 2 {
+		t.Errorf("Expected 3 labels in 2 sets, got %d in %d", d.Size(), d.CountSets())
+	}
+	if roots := d.Roots(); !slices.Equal(roots, []string{"invoice", "refund"}) {
+		t.Errorf("Expected roots [invoice refund], got %v", roots)
+	}
+	if _, ok := d.Sets()[""]; ok {
+		t.Error("Expected removed slots not to appear in Sets")
+	}
+}
+
+func TestDSU_SetSize(t *testing.T) {
+	d := NewDSU()
+	if !d.Observe("billing") || d.Observe("billing") {
+		t.Error("Expected Observe to report only the first use as adding the label")
+	}
+	if d.SetOrigin("billing", OriginLLM) || !d.SetOrigin("invoice", OriginImport) {
+		t.Error("Expected SetOrigin to report whether it added the label")
+	}
+	d.Union(d.FindOrCreate("billing"), d.FindOrCreate("invoice"))
+	d.Union(d.FindOrCreate("billing"), d.FindOrCreate("payment"))
+	d.FindOrCreate("refund")
+
+	sizes := map[string]int{"billing": 3, "payment": 3, "refund": 1, "unknown": 0}
+	for label, size := range sizes {
+		if got := d.SetSize(label); got != size {
+			t.Errorf("Expected %s in a set of %d, got %d", label, size, got)
+		}
+	}
+
+	// Sizes follow removal, compaction and a save and load
+	d.Remove("billing")
+	d.Compact()
+	data, err := d.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	loaded := NewDSU()
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	for _, dsu := range []*DSU{d, loaded} {
+		if dsu.SetSize("payment") != 2 || dsu.SetSize("refund") != 1 || dsu.SetSize("billing") != 0 {
+			t.Errorf("Expected sets of 2 and 1, got %d and %d", dsu.SetSize("payment"), dsu.SetSize("refund"))
+		}
+	}
+}
+
+func TestDSU_Compact(t *testing.T) {
+	d := NewDSU()
+	for _, label := range []string{"a", "b", "c", "d", "e"} {
+		d.Observe(label)
+	}
+	d.Union(d.FindOrCreate("a"), d.FindOrCreate("b"))
+	d.Union(d.FindOrCreate("c"), d.FindOrCreate("d"))
+	d.Union(d.FindOrCreate("a"), d.FindOrCreate("d"))
+	d.SetCanonical("a", "a")
+	d.Remove("a")
+	d.Remove("e")
+	sets := d.Sets()
+
+	if reclaimed := d.Compact(); reclaimed != 2 {
+		t.Errorf("Expected 2 slots reclaimed, got %d", reclaimed)
+	}
+	if reclaimed := d.Compact(); reclaimed != 0 {
+		t.Errorf("Expected a compact DSU to stay as is, got %d slots reclaimed", reclaimed)
+	}
+
+	if len(d.root) != 3 || len(d.count) != 3 || len(d.firstSeen) != 3 {
+		t.Errorf("Expected 3 dense slots, got %d", len(d.root))
+	}
+	if !reflect.DeepEqual(d.Sets(), sets) {
+		t.Errorf("Expected sets %v, got %v", sets, d.Sets())
+	}
+	if canonical := d.Canonical("c"); canonical != "b" {
+		t.Errorf("Expected a removed canonical label to fall back to the root, got %q", canonical)
+	}
+	if usage, _ := d.Usage("d"); usage.Count != 1 {
+		t.Errorf("Expected usage to survive compaction, got %+v", usage)
+	}
+
+	// Indices are dense again, so a new label takes the next slot
+	if idx := d.FindOrCreate("f"); idx != 3 {
+		t.Errorf("Expected new label at index 3, got %d", idx)
+	}
+}
+
+func TestDSU_MergeLabels(t *testing.T) {
+	d := NewDSU()
+	d.SetOrigin("billing", OriginImport)
+	d.SetOrigin("billing", OriginLLM)
+
+	if !d.MergeLabels("invoice", "billing", 0.9) {
+		t.Fatal("Expected invoice to merge into billing")
+	}
+	if d.MergeLabels("invoice", "billing", 0.95) {
+		t.Error("Expected merging labels in the same set to report false")
+	}
+
+	stats, _ := d.Stats("invoice")
+	if stats.Root != "billing" || len(stats.Merges) != 1 || stats.Merges[0].Into != "billing" || stats.Merges[0].Score != 0.9 {
+		t.Errorf("Expected one merge into billing, got %+v", stats)
+	}
+	if stats, _ := d.Stats("billing"); stats.Origin != OriginImport || len(stats.Merges) != 0 {
+		t.Errorf("Expected billing to keep its first origin and no merges, got %+v", stats)
+	}
+
+	// Recording a merge leaves the sets alone
+	d.FindOrCreate("refund")
+	if !d.RecordMerge("refund", "billing", 0.8) || d.RecordMerge("unknown", "billing", 0.8) {
+		t.Error("Expected a merge to be recorded only for known labels")
+	}
+	if stats, _ := d.Stats("refund"); stats.Root != "refund" || len(stats.Merges) != 1 || stats.Merges[0].Score != 0.8 {
+		t.Errorf("Expected refund to stay a root with one recorded merge, got %+v", stats)
+	}
+
+	// Statistics follow their labels through removal and compaction
+	d.FindOrCreate("obsolete")
+	d.Remove("obsolete")
+	d.Compact()
+	if stats, _ := d.Stats("invoice"); len(stats.Merges) != 1 {
+		t.Errorf("Expected the merge history to survive compaction, got %+v", stats)
+	}
+}
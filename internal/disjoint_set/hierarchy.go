@@ -0,0 +1,86 @@
+package disjoint_set
+
+import (
+	"sort"
+)
+
+// SetParent assigns a parent category to the set containing the label
+func (d *DSU) SetParent(label string, parent string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		idx = d.add(label)
+	}
+
+	d.parent[d.find(idx)] = parent
+}
+
+// Parent returns the parent category of the set containing the label, or empty string if none
+func (d *DSU) Parent(label string) string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return ""
+	}
+
+	return d.parent[d.findRoot(idx)]
+}
+
+// Path returns the hierarchy of the label's set from coarse to fine: [parent, root] or [root]
+func (d *DSU) Path(label string) []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return nil
+	}
+
+	root := d.findRoot(idx)
+	if d.parent[root] == "" {
+		return []string{d.labelIndex[root]}
+	}
+
+	return []string{d.parent[root], d.labelIndex[root]}
+}
+
+// Categories returns all distinct parent categories, sorted alphabetically
+func (d *DSU) Categories() []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	seen := make(map[string]bool)
+	categories := make([]string, 0)
+	for i := range d.root {
+		if d.root[i] != i || d.parent[i] == "" || seen[d.parent[i]] {
+			continue
+		}
+		seen[d.parent[i]] = true
+		categories = append(categories, d.parent[i])
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Sets returns the members of every set keyed by root label. Members are sorted alphabetically.
+func (d *DSU) Sets() map[string][]string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	sets := make(map[string][]string)
+	for i := range d.root {
+		if d.removed(i) {
+			continue
+		}
+		rootLabel := d.labelIndex[d.findRoot(i)]
+		sets[rootLabel] = append(sets[rootLabel], d.labelIndex[i])
+	}
+	for _, members := range sets {
+		sort.Strings(members)
+	}
+	return sets
+}
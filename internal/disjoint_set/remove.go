@@ -0,0 +1,138 @@
+package disjoint_set
+
+import "time"
+
+// Removal describes the set a label was removed from, as it was right after the removal
+type Removal struct {
+	Root      string   // Root of the set, empty if no members remain
+	Rerooted  bool     // Whether the removed label was the root, so Root is newly promoted
+	Remaining []string // Labels remaining in the set, in the order they were added
+}
+
+// Remove deletes the label from the DSU. Returns false if it doesn't exist. If the label was the root
+// of a set with other members, the member added first becomes the root and keeps the set's parent
+// category; the set is flagged for the next TakeChanged. The label's slot stays unused until Compact.
+// The returned Removal is taken under the same lock, so it can't be outdated by a concurrent merge.
+func (d *DSU) Remove(label string) (Removal, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return Removal{}, false
+	}
+
+	root := d.find(idx)
+	members := make([]int, 0)
+	for i := range d.root {
+		if i != idx && !d.removed(i) && d.find(i) == root {
+			members = append(members, i)
+		}
+	}
+
+	newRoot := root
+	if root == idx && len(members) > 0 {
+		newRoot = members[0]
+		d.rank[newRoot] = min(1, len(members)-1)
+		d.parent[newRoot] = d.parent[idx]
+		d.canonical[newRoot] = d.canonical[idx]
+		d.changed[newRoot] = struct{}{}
+	}
+	if d.canonical[newRoot] == label {
+		d.canonical[newRoot] = ""
+	}
+	if _, ok := d.changed[idx]; ok && newRoot != idx {
+		d.changed[newRoot] = struct{}{}
+	}
+
+	// Point the members straight at the root, so no path goes through the removed slot
+	removal := Removal{Rerooted: root == idx && len(members) > 0, Remaining: make([]string, len(members))}
+	for j, i := range members {
+		d.root[i] = newRoot
+		removal.Remaining[j] = d.labelIndex[i]
+	}
+	if len(members) > 0 {
+		d.size[newRoot] = len(members)
+		removal.Root = d.labelIndex[newRoot]
+	}
+
+	d.root[idx] = idx
+	d.rank[idx] = 0
+	d.size[idx] = 0
+	d.parent[idx] = ""
+	d.canonical[idx] = ""
+	d.count[idx] = 0
+	d.firstSeen[idx] = time.Time{}
+	d.lastSeen[idx] = time.Time{}
+	d.origin[idx] = OriginUnknown
+	d.merges[idx] = nil
+	delete(d.labels, label)
+	delete(d.labelIndex, idx)
+	delete(d.changed, idx)
+	return removal, true
+}
+
+// Compact rebuilds the DSU without the slots of removed labels, so indices are dense again.
+// Indices returned before Compact are invalidated. Returns the number of slots reclaimed.
+func (d *DSU) Compact() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	reclaimed := len(d.root) - len(d.labels)
+	if reclaimed == 0 {
+		return 0
+	}
+
+	// Keep the order labels were added in, so the member added first stays first
+	index := make(map[int]int, len(d.labels))
+	for i := range d.root {
+		if !d.removed(i) {
+			index[i] = len(index)
+		}
+	}
+
+	n := len(index)
+	root := make([]int, n)
+	rank := make([]int, n)
+	size := make([]int, n)
+	parent := make([]string, n)
+	canonical := make([]string, n)
+	count := make([]int, n)
+	firstSeen := make([]time.Time, n)
+	lastSeen := make([]time.Time, n)
+	origin := make([]Origin, n)
+	merges := make([][]Merge, n)
+	labels := make(map[string]int, n)
+	labelIndex := make(map[int]string, n)
+	for old, idx := range index {
+		root[idx] = index[d.find(old)]
+		rank[idx] = d.rank[old]
+		size[idx] = d.size[old]
+		parent[idx] = d.parent[old]
+		canonical[idx] = d.canonical[old]
+		count[idx] = d.count[old]
+		firstSeen[idx] = d.firstSeen[old]
+		lastSeen[idx] = d.lastSeen[old]
+		origin[idx] = d.origin[old]
+		merges[idx] = d.merges[old]
+		labels[d.labelIndex[old]] = idx
+		labelIndex[idx] = d.labelIndex[old]
+	}
+
+	changed := make(map[int]struct{}, len(d.changed))
+	for old := range d.changed {
+		changed[index[old]] = struct{}{}
+	}
+
+	d.root, d.rank, d.size, d.parent, d.canonical = root, rank, size, parent, canonical
+	d.count, d.firstSeen, d.lastSeen = count, firstSeen, lastSeen
+	d.origin, d.merges = origin, merges
+	d.labels, d.labelIndex, d.changed = labels, labelIndex, changed
+	return reclaimed
+}
+
+// removed reports whether the slot belonged to a removed label (internal, unlocked - caller must hold lock)
+func (d *DSU) removed(idx int) bool {
+	_, ok := d.labelIndex[idx]
+	return !ok
+}
@@ -2,6 +2,7 @@ package disjoint_set
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // MarshalJSON implements json.Marshaler interface
@@ -10,18 +11,32 @@ func (d *DSU) MarshalJSON() ([]byte, error) {
 	defer d.lock.RUnlock()
 
 	return json.Marshal(map[string]interface{}{
-		"root":   d.root,
-		"rank":   d.rank,
-		"labels": d.labels,
+		"root":       d.root,
+		"rank":       d.rank,
+		"parents":    d.parent,
+		"canonical":  d.canonical,
+		"counts":     d.count,
+		"first_seen": d.firstSeen,
+		"last_seen":  d.lastSeen,
+		"origins":    d.origin,
+		"merges":     d.merges,
+		"labels":     d.labels,
 	})
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface
 func (d *DSU) UnmarshalJSON(data []byte) error {
 	var temp struct {
-		Root   []int          `json:"root"`
-		Rank   []int          `json:"rank"`
-		Labels map[string]int `json:"labels"`
+		Root      []int          `json:"root"`
+		Rank      []int          `json:"rank"`
+		Parents   []string       `json:"parents"`
+		Canonical []string       `json:"canonical"`
+		Counts    []int          `json:"counts"`
+		FirstSeen []time.Time    `json:"first_seen"`
+		LastSeen  []time.Time    `json:"last_seen"`
+		Origins   []Origin       `json:"origins"`
+		Merges    [][]Merge      `json:"merges"`
+		Labels    map[string]int `json:"labels"`
 	}
 
 	if err := json.Unmarshal(data, &temp); err != nil {
@@ -31,6 +46,47 @@ func (d *DSU) UnmarshalJSON(data []byte) error {
 	d.root = temp.Root
 	d.rank = temp.Rank
 	d.labels = temp.Labels
+	if d.labels == nil {
+		d.labels = make(map[string]int)
+	}
+
+	// State saved before hierarchical labels has no parents
+	d.parent = padded(temp.Parents, len(d.root))
+
+	// State saved before label usage tracking has no canonical labels, counts or first-seen times
+	d.canonical = padded(temp.Canonical, len(d.root))
+	d.count = padded(temp.Counts, len(d.root))
+	d.firstSeen = padded(temp.FirstSeen, len(d.root))
+
+	// State saved before label statistics has no last-seen times, origins or merge history
+	d.lastSeen = padded(temp.LastSeen, len(d.root))
+	d.origin = padded(temp.Origins, len(d.root))
+	d.merges = padded(temp.Merges, len(d.root))
+
+	// Change tracking is not serialized: loaded state may already be stale
+	d.changed = make(map[int]struct{})
+
+	// Rebuild the reverse index, which is not serialized
+	d.labelIndex = make(map[int]string, len(d.labels))
+	for label, idx := range d.labels {
+		d.labelIndex[idx] = label
+	}
+
+	// Set sizes are not serialized either
+	d.size = make([]int, len(d.root))
+	for i := range d.root {
+		if !d.removed(i) {
+			d.size[d.find(i)]++
+		}
+	}
 
 	return nil
 }
+
+// padded extends values with zero values to length n
+func padded[T any](values []T, n int) []T {
+	if len(values) < n {
+		values = append(values, make([]T, n-len(values))...)
+	}
+	return values
+}
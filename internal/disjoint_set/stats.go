@@ -0,0 +1,130 @@
+package disjoint_set
+
+import (
+	"slices"
+	"time"
+)
+
+// Origin records how a label first entered the DSU
+type Origin string
+
+const (
+	// OriginUnknown is the origin of labels added without one, or saved before origins were tracked
+	OriginUnknown Origin = ""
+
+	// OriginLLM marks labels generated by the LLM
+	OriginLLM Origin = "llm"
+
+	// OriginCorrection marks labels supplied by a human correcting a classification
+	OriginCorrection Origin = "correction"
+
+	// OriginImport marks labels imported from an existing taxonomy or dataset
+	OriginImport Origin = "import"
+)
+
+// Merge records a label joining the set of another label
+type Merge struct {
+	Into  string    `json:"into"`  // Label whose set it joined
+	At    time.Time `json:"at"`    // When it joined
+	Score float32   `json:"score"` // Similarity that justified the merge
+}
+
+// LabelStats describes how a label has been used, where it came from and how it was merged
+type LabelStats struct {
+	Label     string
+	Root      string    // Root label of its set
+	Count     int       // Times the label was observed
+	FirstSeen time.Time // When the label was added
+	LastSeen  time.Time // When the label was last observed, zero if never
+	Origin    Origin
+	Merges    []Merge // Oldest first
+}
+
+// MergeLabels merges the set of label into the set of into, recording the merge and its score in
+// label's history. Labels are added if they don't exist. Returns false if they were already in the same set.
+func (d *DSU) MergeLabels(label string, into string, score float32) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idxInto, ok := d.labels[into]
+	if !ok {
+		idxInto = d.add(into)
+	}
+	idx, ok := d.labels[label]
+	if !ok {
+		idx = d.add(label)
+	}
+	if d.find(idx) == d.find(idxInto) {
+		return false
+	}
+
+	d.union(idxInto, idx)
+	d.merges[idx] = append(d.merges[idx], Merge{Into: into, At: time.Now(), Score: score})
+	return true
+}
+
+// RecordMerge adds a merge into the given label to the label's history without changing any set, e.g.
+// for a rebuilt DSU where the label already joined its new set. Returns false if the label doesn't exist.
+func (d *DSU) RecordMerge(label string, into string, score float32) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return false
+	}
+	d.merges[idx] = append(d.merges[idx], Merge{Into: into, At: time.Now(), Score: score})
+	return true
+}
+
+// SetOrigin records how the label entered the DSU, adding it if it doesn't exist. A known origin is
+// never overwritten. Returns true if the label was added.
+func (d *DSU) SetOrigin(label string, origin Origin) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		idx = d.add(label)
+	}
+	if d.origin[idx] == OriginUnknown {
+		d.origin[idx] = origin
+	}
+	return !ok
+}
+
+// Stats returns the statistics of the label, and false if it doesn't exist
+func (d *DSU) Stats(label string) (LabelStats, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return LabelStats{}, false
+	}
+	return LabelStats{
+		Label:     label,
+		Root:      d.labelIndex[d.findRoot(idx)],
+		Count:     d.count[idx],
+		FirstSeen: d.firstSeen[idx],
+		LastSeen:  d.lastSeen[idx],
+		Origin:    d.origin[idx],
+		Merges:    slices.Clone(d.merges[idx]),
+	}, true
+}
+
+// SetStats overwrites the statistics of the label, adding it if it doesn't exist. Root is ignored.
+func (d *DSU) SetStats(stats LabelStats) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[stats.Label]
+	if !ok {
+		idx = d.add(stats.Label)
+	}
+	d.count[idx] = stats.Count
+	d.firstSeen[idx] = stats.FirstSeen
+	d.lastSeen[idx] = stats.LastSeen
+	d.origin[idx] = stats.Origin
+	d.merges[idx] = slices.Clone(stats.Merges)
+}
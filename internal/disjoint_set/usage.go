@@ -0,0 +1,105 @@
+package disjoint_set
+
+import (
+	"sort"
+	"time"
+)
+
+// LabelUsage describes how a label has been used
+type LabelUsage struct {
+	Label     string
+	Count     int       // Times the label was observed
+	FirstSeen time.Time // When the label was added
+}
+
+// Observe records a use of the label, adding it if it doesn't exist. Returns true if it was added.
+func (d *DSU) Observe(label string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		idx = d.add(label)
+	}
+	d.count[idx]++
+	d.lastSeen[idx] = time.Now()
+	return !ok
+}
+
+// Usage returns the usage of the label, and false if it doesn't exist
+func (d *DSU) Usage(label string) (LabelUsage, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return LabelUsage{}, false
+	}
+	return LabelUsage{Label: label, Count: d.count[idx], FirstSeen: d.firstSeen[idx]}, true
+}
+
+// SetUsage overwrites the usage of the label, adding it if it doesn't exist
+func (d *DSU) SetUsage(usage LabelUsage) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[usage.Label]
+	if !ok {
+		idx = d.add(usage.Label)
+	}
+	d.count[idx] = usage.Count
+	d.firstSeen[idx] = usage.FirstSeen
+}
+
+// Members returns the usage of every label in the label's set, sorted by label. Nil if the label doesn't exist.
+func (d *DSU) Members(label string) []LabelUsage {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return nil
+	}
+
+	root := d.findRoot(idx)
+	members := make([]LabelUsage, 0)
+	for i := range d.root {
+		if d.findRoot(i) == root {
+			members = append(members, LabelUsage{Label: d.labelIndex[i], Count: d.count[i], FirstSeen: d.firstSeen[i]})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Label < members[j].Label
+	})
+	return members
+}
+
+// SetCanonical sets the canonical label of the set containing the label. An empty name resets it to the root label.
+func (d *DSU) SetCanonical(label string, name string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		idx = d.add(label)
+	}
+	d.canonical[d.find(idx)] = name
+}
+
+// Canonical returns the canonical label of the set containing the label: the name set with
+// SetCanonical, or else the root label. Returns the label itself if it doesn't exist.
+func (d *DSU) Canonical(label string) string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	idx, ok := d.labels[label]
+	if !ok {
+		return label
+	}
+
+	root := d.findRoot(idx)
+	if d.canonical[root] != "" {
+		return d.canonical[root]
+	}
+	return d.labelIndex[root]
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Config holds the limits enforced by a Limiter. Zero values disable a limit.
+type Config struct {
+	RequestsPerSecond float64 // Sustained request rate
+	Burst             int     // Requests that may be sent at once. If 0, uses RequestsPerSecond rounded up.
+	TokensPerMinute   int     // Estimated input tokens per minute
+	MaxInFlight       int     // Requests that may be outstanding at once
+}
+
+// Limiter throttles requests to a provider by request rate, token rate and concurrency.
+// A nil Limiter never waits. Safe for concurrent use.
+type Limiter struct {
+	requests *bucket
+	tokens   *bucket
+	inFlight chan struct{}
+	lock     sync.Mutex
+	now      func() time.Time
+}
+
+// New creates a limiter with full buckets
+func New(config Config) *Limiter {
+	l := &Limiter{now: time.Now}
+	start := l.now()
+
+	if config.RequestsPerSecond > 0 {
+		burst := float64(config.Burst)
+		if burst <= 0 {
+			burst = math.Ceil(config.RequestsPerSecond)
+		}
+		l.requests = &bucket{capacity: burst, rate: config.RequestsPerSecond, available: burst, last: start}
+	}
+	if config.TokensPerMinute > 0 {
+		capacity := float64(config.TokensPerMinute)
+		l.tokens = &bucket{capacity: capacity, rate: capacity / 60, available: capacity, last: start}
+	}
+	if config.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+
+	return l
+}
+
+// Wait blocks until a request estimated to use the given number of tokens may be sent, or ctx is done.
+// On success, the caller must call release once the request finishes. waited is the time spent blocked.
+func (l *Limiter) Wait(ctx context.Context, tokens int) (release func(), waited time.Duration, err error) {
+	if l == nil {
+		return func() {}, 0, nil
+	}
+
+	start := time.Now()
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, time.Since(start), ctx.Err()
+		}
+	}
+	release = func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	}
+
+	for {
+		delay := l.reserve(float64(tokens))
+		if delay == 0 {
+			return release, time.Since(start), nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, time.Since(start), ctx.Err()
+		}
+	}
+}
+
+// reserve takes a request and the tokens from the buckets if both have enough, returning 0.
+// Otherwise it takes nothing and returns how long until they might.
+func (l *Limiter) reserve(tokens float64) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.requests.refill(now)
+	l.tokens.refill(now)
+
+	delay := max(l.requests.delay(1), l.tokens.delay(tokens))
+	if delay > 0 {
+		return delay
+	}
+	l.requests.take(1)
+	l.tokens.take(tokens)
+	return 0
+}
+
+// EstimateTokens roughly estimates the tokens in text at four bytes per token
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return max(1, len(text)/4)
+}
+
+// bucket is a token bucket. A nil bucket is unlimited.
+type bucket struct {
+	capacity  float64
+	rate      float64 // units per second
+	available float64
+	last      time.Time
+}
+
+// refill adds the units accrued since the last refill
+func (b *bucket) refill(now time.Time) {
+	if b == nil {
+		return
+	}
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	if elapsed > 0 {
+		b.available = min(b.capacity, b.available+elapsed*b.rate)
+	}
+}
+
+// delay returns how long until n units are available. Requests larger than the bucket
+// only wait for it to fill, so they can't block forever.
+func (b *bucket) delay(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	n = min(n, b.capacity)
+	if b.available >= n {
+		return 0
+	}
+	return max(time.Nanosecond, time.Duration((n-b.available)/b.rate*float64(time.Second)))
+}
+
+// take removes n units, which may leave the bucket in debt for requests larger than it
+func (b *bucket) take(n float64) {
+	if b == nil {
+		return
+	}
+	b.available -= n
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_RequestsPerSecond(t *testing.T) {
+	now := time.Now()
+	limiter := New(Config{RequestsPerSecond: 2})
+	limiter.now = func() time.Time { return now }
+	limiter.requests.last = now
+
+	for range 2 {
+		if delay := limiter.reserve(0); delay != 0 {
+			t.Fatalf("Expected burst to be allowed, got delay %v", delay)
+		}
+	}
+	if delay := limiter.reserve(0); delay != 500*time.Millisecond {
+		t.Fatalf("Expected 500ms delay once the burst is spent, got %v", delay)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if delay := limiter.reserve(0); delay != 0 {
+		t.Fatalf("Expected request to be allowed after refill, got delay %v", delay)
+	}
+}
+
+func TestLimiter_TokensPerMinute(t *testing.T) {
+	now := time.Now()
+	limiter := New(Config{TokensPerMinute: 600})
+	limiter.now = func() time.Time { return now }
+	limiter.tokens.last = now
+
+	if delay := limiter.reserve(500); delay != 0 {
+		t.Fatalf("Expected request within the budget to be allowed, got delay %v", delay)
+	}
+	// 100 tokens left, refilling 10 per second
+	if delay := limiter.reserve(200); delay != 10*time.Second {
+		t.Fatalf("Expected 10s delay, got %v", delay)
+	}
+
+	// Requests larger than the bucket only wait for it to fill
+	now = now.Add(50 * time.Second)
+	if delay := limiter.reserve(1000); delay != 0 {
+		t.Fatalf("Expected oversized request to be allowed once the bucket is full, got delay %v", delay)
+	}
+}
+
+func TestLimiter_MaxInFlight(t *testing.T) {
+	limiter := New(Config{MaxInFlight: 2})
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, _, err := limiter.Wait(context.Background(), 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+
+			current := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if current <= p || peak.CompareAndSwap(p, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", peak.Load())
+	}
+}
+
+func TestLimiter_WaitCanceled(t *testing.T) {
+	limiter := New(Config{RequestsPerSecond: 1, MaxInFlight: 1})
+	release, _, err := limiter.Wait(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, waited, err := limiter.Wait(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if waited < 10*time.Millisecond {
+		t.Errorf("Expected to wait until the deadline, waited %v", waited)
+	}
+
+	// The canceled wait must not hold the in-flight slot
+	select {
+	case limiter.inFlight <- struct{}{}:
+	default:
+		t.Error("Expected canceled wait to release its in-flight slot")
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var limiter *Limiter
+	release, waited, err := limiter.Wait(context.Background(), 100)
+	if err != nil || waited != 0 {
+		t.Fatalf("Expected nil limiter not to wait, got %v, %v", waited, err)
+	}
+	release()
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := map[string]int{"": 0, "hi": 1, "sixteen chars...": 4}
+	for text, want := range tests {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors returned while a circuit breaker is rejecting calls
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets all calls through and tracks their failure rate
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all calls until the cool-down elapses
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through to test whether the provider recovered
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig holds the configuration for a circuit breaker
+type BreakerConfig struct {
+	Window         time.Duration // Period over which the failure rate is measured
+	MinRequests    int           // Calls required in the window before the breaker can trip
+	FailureRate    float64       // Fraction of failed calls (0-1) that trips the breaker
+	CoolDown       time.Duration // How long the breaker stays open before allowing a probe
+	HalfOpenProbes int           // Successful probes required to close the breaker again
+}
+
+// DefaultBreakerConfig returns a sensible default circuit breaker configuration
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Window:         30 * time.Second,
+		MinRequests:    10,
+		FailureRate:    0.5,
+		CoolDown:       15 * time.Second,
+		HalfOpenProbes: 1,
+	}
+}
+
+// CircuitOpenError is returned when a call is rejected by an open circuit breaker
+type CircuitOpenError struct {
+	Name    string
+	RetryAt time.Time // When the breaker will allow a probe call
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "circuit breaker is open for " + e.Name
+}
+
+// Is reports whether target is ErrCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// Breaker is a circuit breaker that fails calls fast while a provider is unhealthy.
+// Callers check Allow before each call and report its outcome with Record, or Release it
+// if the outcome says nothing about the provider's health.
+// All methods are safe to call on a nil breaker, which allows every call.
+type Breaker struct {
+	name          string
+	config        BreakerConfig
+	state         BreakerState
+	windowStart   time.Time
+	requests      int
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+	probeSuccess  int
+	now           func() time.Time
+	lock          sync.Mutex
+}
+
+// NewBreaker creates a closed circuit breaker. name identifies it in errors.
+func NewBreaker(name string, config BreakerConfig) *Breaker {
+	return &Breaker{
+		name:        name,
+		config:      config,
+		windowStart: time.Now(),
+		now:         time.Now,
+	}
+}
+
+// Allow returns a *CircuitOpenError if the breaker is rejecting calls
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.config.CoolDown {
+			return &CircuitOpenError{Name: b.name, RetryAt: b.openedAt.Add(b.config.CoolDown)}
+		}
+		b.state = BreakerHalfOpen
+		b.probeSuccess = 0
+		b.probeInFlight = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probeInFlight {
+			return &CircuitOpenError{Name: b.name, RetryAt: now}
+		}
+		b.probeInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of a call let through by Allow. Only failures that indicate an
+// unhealthy provider (network errors, rate limits, server errors) should be recorded as failed.
+func (b *Breaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probeInFlight = false
+		if failed {
+			b.trip(now)
+			return
+		}
+		b.probeSuccess++
+		if b.probeSuccess >= max(1, b.config.HalfOpenProbes) {
+			b.state = BreakerClosed
+			b.resetWindow(now)
+		}
+	case BreakerClosed:
+		if now.Sub(b.windowStart) > b.config.Window {
+			b.resetWindow(now)
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.config.MinRequests && float64(b.failures)/float64(b.requests) >= b.config.FailureRate {
+			b.trip(now)
+		}
+	}
+}
+
+// Release hands back a call let through by Allow without recording an outcome, e.g. because the
+// caller gave up on it. A half-open breaker lets the next call through as its probe.
+func (b *Breaker) Release() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.probeInFlight = false
+	}
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.CoolDown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// trip opens the breaker. Caller must hold the lock.
+func (b *Breaker) trip(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.resetWindow(now)
+}
+
+// resetWindow starts a new failure-rate window. Caller must hold the lock.
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+// CallerDone reports whether a call failed because its caller gave up: the call was cancelled or
+// the caller's deadline passed. Such failures should be released rather than recorded.
+func CallerDone(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || ctx.Err() != nil
+}
+
+// IsProviderFailure reports whether a call that failed with err indicates an unhealthy provider, so
+// it counts towards tripping the breaker. statusCode is the HTTP status of the last response, or 0 if
+// there was none. Rate limits, server errors, network errors and attempts timing out count; bad
+// requests, local encoding or decoding errors, rejections by the breaker itself and calls the caller
+// gave up on don't.
+func IsProviderFailure(ctx context.Context, err error, statusCode int) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) || CallerDone(ctx, err) {
+		return false
+	}
+	if statusCode != 0 {
+		return statusCode == http.StatusTooManyRequests || statusCode >= 500
+	}
+
+	// Without a response, only failures to reach the provider count
+	return IsTransportError(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsTransportError reports whether err is a failure to reach the provider or to read its response,
+// as opposed to a local failure such as encoding a request or decoding a response
+func IsTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,163 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewBreaker("test", BreakerConfig{
+		Window:         time.Minute,
+		MinRequests:    4,
+		FailureRate:    0.5,
+		CoolDown:       10 * time.Second,
+		HalfOpenProbes: 1,
+	})
+	breaker.now = func() time.Time { return now }
+	breaker.windowStart = now
+
+	// Stays closed until enough requests are seen
+	for _, failed := range []bool{true, true, false} {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Expected closed breaker to allow calls, got %v", err)
+		}
+		breaker.Record(failed)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("Expected breaker to stay closed below MinRequests, got %s", breaker.State())
+	}
+
+	// The fourth request reaches the failure rate and trips it
+	breaker.Record(true)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("Expected breaker to open, got %s", breaker.State())
+	}
+
+	err := breaker.Allow()
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected CircuitOpenError, got %v", err)
+	}
+	if !openErr.RetryAt.Equal(now.Add(10 * time.Second)) {
+		t.Errorf("Expected RetryAt after cool-down, got %v", openErr.RetryAt)
+	}
+
+	// After the cool-down a single probe is allowed
+	now = now.Add(10 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected probe to be allowed, got %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected concurrent probe to be rejected, got %v", err)
+	}
+
+	// A failed probe reopens the breaker
+	breaker.Record(true)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("Expected failed probe to reopen breaker, got %s", breaker.State())
+	}
+
+	// A successful probe closes it
+	now = now.Add(10 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected probe to be allowed, got %v", err)
+	}
+	breaker.Record(false)
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("Expected successful probe to close breaker, got %s", breaker.State())
+	}
+}
+
+func TestBreaker_WindowReset(t *testing.T) {
+	now := time.Now()
+	breaker := NewBreaker("test", BreakerConfig{Window: time.Second, MinRequests: 2, FailureRate: 1, CoolDown: time.Second})
+	breaker.now = func() time.Time { return now }
+	breaker.windowStart = now
+
+	breaker.Record(true)
+	now = now.Add(2 * time.Second)
+	breaker.Record(true)
+
+	if breaker.State() != BreakerClosed {
+		t.Errorf("Expected failures in separate windows not to trip the breaker, got %s", breaker.State())
+	}
+}
+
+func TestBreaker_Nil(t *testing.T) {
+	var breaker *Breaker
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("Expected nil breaker to allow calls, got %v", err)
+	}
+	breaker.Record(true)
+	if breaker.State() != BreakerClosed {
+		t.Errorf("Expected nil breaker to report closed, got %s", breaker.State())
+	}
+}
+
+func TestBreaker_Release(t *testing.T) {
+	now := time.Now()
+	breaker := NewBreaker("test", BreakerConfig{Window: time.Minute, MinRequests: 1, FailureRate: 0.5, CoolDown: time.Second})
+	breaker.now = func() time.Time { return now }
+	breaker.Record(true)
+
+	// A released probe neither closes nor re-opens the breaker, and frees the slot for the next probe
+	now = now.Add(2 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected a probe after the cool-down, got %v", err)
+	}
+	breaker.Release()
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("Expected breaker to stay half-open, got %s", breaker.State())
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected the released probe to free the slot, got %v", err)
+	}
+	breaker.Record(false)
+	if breaker.State() != BreakerClosed {
+		t.Errorf("Expected a successful probe to close the breaker, got %s", breaker.State())
+	}
+
+	var nilBreaker *Breaker
+	nilBreaker.Release()
+}
+
+func TestIsProviderFailure(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		statusCode int
+		want       bool
+	}{
+		{"success", context.Background(), nil, 200, false},
+		{"rate limited", context.Background(), errors.New("slow down"), 429, true},
+		{"server error", context.Background(), errors.New("boom"), 503, true},
+		{"bad request", context.Background(), errors.New("bad"), 400, false},
+		{"network error", context.Background(), &net.OpError{Op: "dial", Err: errors.New("connection refused")}, 0, true},
+		{"connection reset", context.Background(), fmt.Errorf("failed to read response: %w", syscall.ECONNRESET), 0, true},
+		{"attempt timed out", context.Background(), context.DeadlineExceeded, 0, true},
+		{"decode error", context.Background(), &json.SyntaxError{}, 0, false},
+		{"caller cancelled", context.Background(), context.Canceled, 0, false},
+		{"caller deadline", cancelled, context.DeadlineExceeded, 0, false},
+		{"server error after caller gave up", cancelled, errors.New("boom"), 503, false},
+		{"circuit open", context.Background(), &CircuitOpenError{Name: "test"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsProviderFailure(tt.ctx, tt.err, tt.statusCode); got != tt.want {
+				t.Errorf("IsProviderFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
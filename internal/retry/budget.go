@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Budget is a token bucket shared across calls that limits how many retries can be made.
+// Each retry takes one token; tokens refill at a fixed rate up to the bucket's capacity.
+type Budget struct {
+	capacity   float64
+	refillRate float64 // tokens per second
+	tokens     float64
+	last       time.Time
+	now        func() time.Time
+	lock       sync.Mutex
+}
+
+// NewBudget creates a full budget holding up to capacity retries and refilling refillPerSecond retries per second
+func NewBudget(capacity int, refillPerSecond float64) *Budget {
+	return &Budget{
+		capacity:   float64(capacity),
+		refillRate: refillPerSecond,
+		tokens:     float64(capacity),
+		last:       time.Now(),
+		now:        time.Now,
+	}
+}
+
+// Allow takes a token from the budget and reports whether a retry may proceed. A nil budget always allows.
+func (b *Budget) Allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Available returns the number of whole retries currently left in the budget. A nil budget is unlimited.
+func (b *Budget) Available() int {
+	if b == nil {
+		return math.MaxInt
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.refill()
+	return int(b.tokens)
+}
+
+// refill adds the tokens accrued since the last refill. Caller must hold the lock.
+func (b *Budget) refill() {
+	now := b.now()
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	if elapsed > 0 {
+		b.tokens = min(b.capacity, b.tokens+elapsed*b.refillRate)
+	}
+}
@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerDelay returns how long the server asked the client to wait before retrying, or 0 if it didn't.
+// It reads Retry-After (seconds or HTTP date) and retry-after-ms on any response, and the
+// x-ratelimit-reset-requests / x-ratelimit-reset-tokens headers OpenAI sends with 429 responses.
+func ServerDelay(statusCode int, header http.Header, now time.Time) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	var delay time.Duration
+	if value := header.Get("retry-after-ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms > 0 {
+			delay = max(delay, time.Duration(ms*float64(time.Millisecond)))
+		}
+	}
+	if value := header.Get("Retry-After"); value != "" {
+		delay = max(delay, parseRetryAfter(value, now))
+	}
+
+	if statusCode == http.StatusTooManyRequests {
+		for _, key := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+			if value := header.Get(key); value != "" {
+				if d, err := time.ParseDuration(strings.TrimSpace(value)); err == nil && d > 0 {
+					delay = max(delay, d)
+				}
+			}
+		}
+	}
+
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After value given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
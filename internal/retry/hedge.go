@@ -0,0 +1,170 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HedgeConfig configures when a Hedger sends a second, hedged attempt
+type HedgeConfig struct {
+	Percentile float64       // Latency percentile of recent attempts after which a hedge is sent, e.g. 0.95
+	MinDelay   time.Duration // Lower bound on the hedge delay
+	MaxDelay   time.Duration // Upper bound on the hedge delay, also used until MinSamples latencies are observed. 0 means no bound.
+	Window     int           // Number of recent latencies tracked
+	MinSamples int           // Latencies needed before the percentile is used
+}
+
+// DefaultHedgeConfig hedges attempts slower than the p95 of the last 100 successful attempts
+func DefaultHedgeConfig() HedgeConfig {
+	return HedgeConfig{
+		Percentile: 0.95,
+		MinDelay:   10 * time.Millisecond,
+		MaxDelay:   2 * time.Second,
+		Window:     100,
+		MinSamples: 20,
+	}
+}
+
+// Hedger tracks recent attempt latencies to decide when to hedge a slow attempt.
+// A nil Hedger never hedges. Safe for concurrent use.
+type Hedger struct {
+	config HedgeConfig
+
+	mu        sync.Mutex
+	latencies []time.Duration // Ring buffer of the last config.Window latencies
+	next      int
+}
+
+// NewHedger creates a hedger with no observed latencies
+func NewHedger(config HedgeConfig) *Hedger {
+	if config.Window <= 0 {
+		config.Window = DefaultHedgeConfig().Window
+	}
+	return &Hedger{config: config, latencies: make([]time.Duration, 0, config.Window)}
+}
+
+// Delay returns how long to wait for an attempt before sending a hedge
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) == 0 || len(h.latencies) < h.config.MinSamples {
+		// Without a bound to fall back on, warm-up attempts would be hedged immediately
+		if h.config.MaxDelay <= 0 {
+			return DefaultHedgeConfig().MaxDelay
+		}
+		return h.config.MaxDelay
+	}
+
+	sorted := slices.Clone(h.latencies)
+	slices.Sort(sorted)
+	index := int(h.config.Percentile * float64(len(sorted)))
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	delay := max(sorted[index], h.config.MinDelay)
+	if h.config.MaxDelay > 0 {
+		delay = min(delay, h.config.MaxDelay)
+	}
+	return delay
+}
+
+// Observe records the latency of a successful attempt
+func (h *Hedger) Observe(latency time.Duration) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < h.config.Window {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % h.config.Window
+}
+
+// HedgeHook is called when a hedged attempt finishes a call. won reports whether the hedge,
+// rather than the original attempt, returned the result.
+type HedgeHook func(apiName string, won bool)
+
+// Hedge runs fn and, if it hasn't returned after h.Delay(), runs it a second time and returns
+// whichever succeeds first. The slower attempt is cancelled. fn must be idempotent.
+// If h is nil, fn is called once.
+func Hedge[T any](ctx context.Context, h *Hedger, onHedge func(won bool), fn func(ctx context.Context) (T, error)) (T, error) {
+	if h == nil {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		result T
+		err    error
+		hedge  bool
+	}
+	outcomes := make(chan outcome, 2)
+	run := func(hedge bool) {
+		start := time.Now()
+		result, err := fn(ctx)
+		if err == nil {
+			h.Observe(time.Since(start))
+		}
+		outcomes <- outcome{result: result, err: err, hedge: hedge}
+	}
+
+	go run(false)
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+
+	hedged := false
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			hedged = true
+			pending++
+			go run(true)
+		case o := <-outcomes:
+			pending--
+			// Wait for the other attempt if this one failed while it is still running
+			if o.err != nil && pending > 0 {
+				continue
+			}
+			if hedged && onHedge != nil {
+				onHedge(o.hedge)
+			}
+			return o.result, o.err
+		}
+	}
+}
+
+// hedgeRetryableFn wraps fn so each attempt is hedged
+func hedgeRetryableFn(opts Options, fn RetryableFunc) RetryableFunc {
+	type attemptResult struct {
+		result       interface{}
+		statusCode   int
+		header       http.Header
+		responseBody []byte
+	}
+
+	return func(ctx context.Context, attempt int) (interface{}, int, http.Header, []byte, error) {
+		var onHedge func(won bool)
+		if opts.OnHedge != nil {
+			onHedge = func(won bool) { opts.OnHedge(opts.APIName, won) }
+		}
+
+		// The result of a failed attempt is kept so the error checker can classify its status
+		r, err := Hedge(ctx, opts.Hedger, onHedge, func(ctx context.Context) (attemptResult, error) {
+			result, statusCode, header, responseBody, err := fn(ctx, attempt)
+			return attemptResult{result: result, statusCode: statusCode, header: header, responseBody: responseBody}, err
+		})
+		return r.result, r.statusCode, r.header, r.responseBody, err
+	}
+}
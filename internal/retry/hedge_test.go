@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedger_Delay(t *testing.T) {
+	hedger := NewHedger(HedgeConfig{
+		Percentile: 0.9,
+		MinDelay:   5 * time.Millisecond,
+		MaxDelay:   time.Second,
+		Window:     10,
+		MinSamples: 5,
+	})
+
+	if delay := hedger.Delay(); delay != time.Second {
+		t.Errorf("Expected MaxDelay before enough samples, got %v", delay)
+	}
+
+	for i := 1; i <= 10; i++ {
+		hedger.Observe(time.Duration(i) * 10 * time.Millisecond)
+	}
+	if delay := hedger.Delay(); delay != 100*time.Millisecond {
+		t.Errorf("Expected p90 of 100ms, got %v", delay)
+	}
+
+	// Old latencies fall out of the window
+	for range 10 {
+		hedger.Observe(time.Millisecond)
+	}
+	if delay := hedger.Delay(); delay != 5*time.Millisecond {
+		t.Errorf("Expected delay clamped to MinDelay, got %v", delay)
+	}
+
+	unbounded := NewHedger(HedgeConfig{Percentile: 0.9, MinSamples: 5})
+	if delay := unbounded.Delay(); delay != DefaultHedgeConfig().MaxDelay {
+		t.Errorf("Expected the default MaxDelay before enough samples without a bound, got %v", delay)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	// MinSamples keeps the delay at MaxDelay, whatever latencies the subtests observe
+	hedger := NewHedger(HedgeConfig{MaxDelay: 10 * time.Millisecond, MinSamples: 100})
+
+	t.Run("hedge wins when the first attempt is slow", func(t *testing.T) {
+		var calls atomic.Int32
+		var won *bool
+		result, err := Hedge(context.Background(), hedger, func(w bool) { won = &w }, func(ctx context.Context) (int, error) {
+			call := calls.Add(1)
+			if call == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return 2, nil
+		})
+		if err != nil || result != 2 {
+			t.Fatalf("Expected hedge result, got %d, %v", result, err)
+		}
+		if won == nil || !*won {
+			t.Error("Expected hedge hook to report a hedge win")
+		}
+	})
+
+	t.Run("fast attempt is not hedged", func(t *testing.T) {
+		var calls atomic.Int32
+		hooked := false
+		result, err := Hedge(context.Background(), hedger, func(bool) { hooked = true }, func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			return 1, nil
+		})
+		if err != nil || result != 1 {
+			t.Fatalf("Expected result, got %d, %v", result, err)
+		}
+		if calls.Load() != 1 || hooked {
+			t.Errorf("Expected a single attempt, got %d", calls.Load())
+		}
+	})
+
+	t.Run("failed attempt waits for the hedge", func(t *testing.T) {
+		var calls atomic.Int32
+		result, err := Hedge(context.Background(), hedger, nil, func(ctx context.Context) (int, error) {
+			if calls.Add(1) == 1 {
+				time.Sleep(20 * time.Millisecond)
+				return 0, errors.New("slow failure")
+			}
+			time.Sleep(20 * time.Millisecond)
+			return 2, nil
+		})
+		if err != nil || result != 2 {
+			t.Fatalf("Expected hedge result, got %d, %v", result, err)
+		}
+	})
+
+	t.Run("nil hedger calls once", func(t *testing.T) {
+		var calls atomic.Int32
+		_, _ = Hedge(context.Background(), nil, nil, func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			return 1, nil
+		})
+		if calls.Load() != 1 {
+			t.Errorf("Expected a single attempt, got %d", calls.Load())
+		}
+	})
+}
+
+func TestExecute_Hedged(t *testing.T) {
+	var calls atomic.Int32
+	var hedges atomic.Int32
+	opts := Options{
+		Hedger:  NewHedger(HedgeConfig{MaxDelay: 10 * time.Millisecond}),
+		OnHedge: func(apiName string, won bool) { hedges.Add(1) },
+		APIName: "test",
+	}
+
+	result, err := Execute(context.Background(), opts, func(ctx context.Context, attempt int) (interface{}, int, http.Header, []byte, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return nil, 0, nil, nil, ctx.Err()
+		}
+		return "ok", http.StatusOK, nil, nil, nil
+	})
+	if err != nil || result != "ok" {
+		t.Fatalf("Expected hedged result, got %v, %v", result, err)
+	}
+	if hedges.Load() != 1 {
+		t.Errorf("Expected 1 hedge, got %d", hedges.Load())
+	}
+}
@@ -2,8 +2,26 @@ package retry
 
 import (
 	"context"
+	"log/slog"
 	"math"
+	"math/rand/v2"
+	"net/http"
 	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JitterMode controls how retry delays are randomized
+type JitterMode int
+
+const (
+	// JitterNone uses the exponential backoff delay as-is
+	JitterNone JitterMode = iota
+	// JitterFull picks a random delay between 0 and the exponential backoff delay
+	JitterFull
+	// JitterDecorrelated picks a random delay between BaseDelay and 3x the previous delay
+	JitterDecorrelated
 )
 
 // Config holds the configuration for retry logic
@@ -12,6 +30,8 @@ type Config struct {
 	BaseDelay       time.Duration
 	MaxDelay        time.Duration
 	BackoffMultiple float64
+	Jitter          JitterMode
+	MaxServerDelay  time.Duration // Upper bound on delays requested by the server. 0 means no bound.
 }
 
 // DefaultConfig returns a sensible default retry configuration
@@ -21,23 +41,31 @@ func DefaultConfig() Config {
 		BaseDelay:       200 * time.Millisecond,
 		MaxDelay:        5 * time.Second,
 		BackoffMultiple: 2.0,
+		Jitter:          JitterFull,
+		MaxServerDelay:  time.Minute,
 	}
 }
 
 // ErrorChecker defines a function that determines if an error should trigger a retry
 type ErrorChecker func(err error, statusCode int, responseBody []byte) bool
 
-// RetryableFunc defines a function that can be retried
-type RetryableFunc func(attempt int) (result interface{}, statusCode int, responseBody []byte, err error)
+// RetryableFunc defines a function that can be retried. ctx carries the span of the current attempt.
+// header holds the response headers, if any, so server-requested delays can be honoured.
+type RetryableFunc func(ctx context.Context, attempt int) (result interface{}, statusCode int, header http.Header, responseBody []byte, err error)
 
-// Logger defines a function for logging retry attempts
-type Logger func(message string, args ...interface{})
+// RetryHook is called before each retry attempt (not the first attempt)
+type RetryHook func(apiName string, attempt int)
 
 // Options configures retry behavior
 type Options struct {
 	Config       Config
 	ErrorChecker ErrorChecker
-	Logger       Logger
+	Logger       *slog.Logger // Optional. Logs retry attempts with structured fields.
+	OnRetry      RetryHook
+	Tracer       trace.Tracer // Optional. Creates a span per attempt.
+	Budget       *Budget      // Optional. Shared budget that each retry draws from.
+	Hedger       *Hedger      // Optional. Hedges slow attempts. Only set for idempotent calls.
+	OnHedge      HedgeHook
 	APIName      string
 }
 
@@ -50,18 +78,60 @@ func (c Config) calculateDelay(attempt int) time.Duration {
 	return delay
 }
 
+// nextDelay computes the delay before the given retry, applying jitter to the backoff and
+// waiting at least as long as the server requested
+func (c Config) nextDelay(attempt int, previous time.Duration, serverDelay time.Duration) time.Duration {
+	var delay time.Duration
+	switch c.Jitter {
+	case JitterFull:
+		delay = randomDuration(0, c.calculateDelay(attempt))
+	case JitterDecorrelated:
+		delay = randomDuration(c.BaseDelay, max(c.BaseDelay, previous*3))
+		if delay > c.MaxDelay {
+			delay = c.MaxDelay
+		}
+	default:
+		delay = c.calculateDelay(attempt)
+	}
+
+	if c.MaxServerDelay > 0 && serverDelay > c.MaxServerDelay {
+		serverDelay = c.MaxServerDelay
+	}
+	return max(delay, serverDelay)
+}
+
+// randomDuration returns a random duration in [low, high]
+func randomDuration(low time.Duration, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	return low + rand.N(high-low+1)
+}
+
 // Execute performs the retryable function with the configured retry logic
 func Execute(ctx context.Context, opts Options, fn RetryableFunc) (interface{}, error) {
 	var lastErr error
 	var lastStatusCode int
 	var lastResponseBody []byte
+	var delay time.Duration
+	budgetExhausted := false
+	if opts.Hedger != nil {
+		fn = hedgeRetryableFn(opts, fn)
+	}
 
 	for attempt := 0; attempt <= opts.Config.MaxRetries; attempt++ {
 		// Add delay before retry (but not on first attempt)
 		if attempt > 0 {
-			delay := opts.Config.calculateDelay(attempt - 1)
 			if opts.Logger != nil {
-				opts.Logger("%s API retry attempt %d/%d after %v delay", opts.APIName, attempt+1, opts.Config.MaxRetries+1, delay)
+				opts.Logger.LogAttrs(ctx, slog.LevelInfo, "retrying API request",
+					slog.String("api", opts.APIName),
+					slog.Int("attempt", attempt+1),
+					slog.Int("max_attempts", opts.Config.MaxRetries+1),
+					slog.Duration("delay", delay),
+				)
+			}
+			if opts.OnRetry != nil {
+				opts.OnRetry(opts.APIName, attempt)
 			}
 
 			// Check for context cancellation during delay
@@ -73,19 +143,44 @@ func Execute(ctx context.Context, opts Options, fn RetryableFunc) (interface{},
 		}
 
 		// Execute the function
-		result, statusCode, responseBody, err := fn(attempt)
+		attemptCtx, span := tracing.Start(ctx, opts.Tracer, "retry.attempt",
+			tracing.AttrAPIName.String(opts.APIName),
+			tracing.AttrAttempt.Int(attempt+1),
+		)
+		attemptStart := time.Now()
+		result, statusCode, header, responseBody, err := fn(attemptCtx, attempt)
+		latency := time.Since(attemptStart)
+		if statusCode != 0 {
+			span.SetAttributes(tracing.AttrStatusCode.Int(statusCode))
+		}
+		tracing.End(span, err)
+
 		lastErr = err
 		lastStatusCode = statusCode
 		lastResponseBody = responseBody
 
 		// Check if this is a retryable error
-		if opts.ErrorChecker != nil && opts.ErrorChecker(err, statusCode, responseBody) && attempt < opts.Config.MaxRetries {
+		retryable := opts.ErrorChecker != nil && opts.ErrorChecker(err, statusCode, responseBody)
+		if retryable && attempt < opts.Config.MaxRetries {
+			// Stop retrying once the shared budget is spent, so an outage doesn't multiply traffic
+			if !opts.Budget.Allow() {
+				budgetExhausted = true
+				break
+			}
+			delay = opts.Config.nextDelay(attempt, delay, ServerDelay(statusCode, header, time.Now()))
+
 			if opts.Logger != nil {
+				attrs := []slog.Attr{
+					slog.String("api", opts.APIName),
+					slog.Int("attempt", attempt+1),
+					slog.Int("max_attempts", opts.Config.MaxRetries+1),
+					slog.Int("status_code", statusCode),
+					slog.Duration("latency", latency),
+				}
 				if err != nil {
-					opts.Logger("%s API network error (attempt %d/%d): %v", opts.APIName, attempt+1, opts.Config.MaxRetries+1, err)
-				} else {
-					opts.Logger("%s API retryable error (attempt %d/%d): status %d", opts.APIName, attempt+1, opts.Config.MaxRetries+1, statusCode)
+					attrs = append(attrs, slog.Any("error", err))
 				}
+				opts.Logger.LogAttrs(ctx, slog.LevelWarn, "retryable API error", attrs...)
 			}
 			continue
 		}
@@ -93,37 +188,57 @@ func Execute(ctx context.Context, opts Options, fn RetryableFunc) (interface{},
 		// If no error or non-retryable error, return the result
 		if err == nil {
 			if attempt > 0 && opts.Logger != nil {
-				opts.Logger("%s API request succeeded on attempt %d/%d", opts.APIName, attempt+1, opts.Config.MaxRetries+1)
+				opts.Logger.LogAttrs(ctx, slog.LevelInfo, "API request succeeded after retry",
+					slog.String("api", opts.APIName),
+					slog.Int("attempt", attempt+1),
+					slog.Int("max_attempts", opts.Config.MaxRetries+1),
+					slog.Duration("latency", latency),
+				)
 			}
 			return result, nil
 		}
 
-		// Non-retryable error, return immediately
-		return nil, err
+		// Non-retryable error (or no retries configured), return immediately
+		if !retryable || attempt == 0 {
+			return nil, err
+		}
 	}
 
 	// All retries exhausted
-	if lastErr != nil {
-		return nil, lastErr
-	}
-
-	// This shouldn't happen, but return a generic error if it does
 	return nil, &RetryExhaustedError{
-		APIName:        opts.APIName,
-		MaxAttempts:    opts.Config.MaxRetries + 1,
-		LastStatusCode: lastStatusCode,
-		LastResponse:   lastResponseBody,
+		APIName:         opts.APIName,
+		MaxAttempts:     opts.Config.MaxRetries + 1,
+		LastStatusCode:  lastStatusCode,
+		LastResponse:    lastResponseBody,
+		BudgetExhausted: budgetExhausted,
+		Err:             lastErr,
 	}
 }
 
 // RetryExhaustedError represents an error when all retry attempts have been exhausted
 type RetryExhaustedError struct {
-	APIName        string
-	MaxAttempts    int
-	LastStatusCode int
-	LastResponse   []byte
+	APIName         string
+	MaxAttempts     int
+	LastStatusCode  int
+	LastResponse    []byte
+	BudgetExhausted bool  // Retries stopped early because the retry budget was spent
+	Err             error // Error returned by the last attempt, if any
 }
 
 func (e *RetryExhaustedError) Error() string {
+	if e.BudgetExhausted {
+		if e.Err != nil {
+			return "retry budget exhausted for " + e.APIName + " API: " + e.Err.Error()
+		}
+		return "retry budget exhausted for " + e.APIName + " API"
+	}
+	if e.Err != nil {
+		return "retry attempts exhausted for " + e.APIName + " API: " + e.Err.Error()
+	}
 	return "retry attempts exhausted for " + e.APIName + " API"
 }
+
+// Unwrap returns the error of the last attempt so callers can inspect it with errors.As
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,159 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConfig_NextDelay(t *testing.T) {
+	cfg := Config{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffMultiple: 2}
+
+	t.Run("no jitter", func(t *testing.T) {
+		if delay := cfg.nextDelay(2, 0, 0); delay != 400*time.Millisecond {
+			t.Errorf("Expected 400ms, got %v", delay)
+		}
+	})
+
+	t.Run("full jitter", func(t *testing.T) {
+		cfg := cfg
+		cfg.Jitter = JitterFull
+		for range 100 {
+			if delay := cfg.nextDelay(2, 0, 0); delay < 0 || delay > 400*time.Millisecond {
+				t.Fatalf("Expected delay in [0, 400ms], got %v", delay)
+			}
+		}
+	})
+
+	t.Run("decorrelated jitter", func(t *testing.T) {
+		cfg := cfg
+		cfg.Jitter = JitterDecorrelated
+		for range 100 {
+			delay := cfg.nextDelay(3, 200*time.Millisecond, 0)
+			if delay < 100*time.Millisecond || delay > 600*time.Millisecond {
+				t.Fatalf("Expected delay in [100ms, 600ms], got %v", delay)
+			}
+		}
+		if delay := cfg.nextDelay(3, 10*time.Second, 0); delay > time.Second {
+			t.Errorf("Expected delay capped at MaxDelay, got %v", delay)
+		}
+	})
+
+	t.Run("server delay wins", func(t *testing.T) {
+		if delay := cfg.nextDelay(0, 0, 3*time.Second); delay != 3*time.Second {
+			t.Errorf("Expected server delay of 3s, got %v", delay)
+		}
+
+		cfg := cfg
+		cfg.MaxServerDelay = 2 * time.Second
+		if delay := cfg.nextDelay(0, 0, time.Hour); delay != 2*time.Second {
+			t.Errorf("Expected server delay capped at 2s, got %v", delay)
+		}
+	})
+}
+
+func TestServerDelay(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		statusCode int
+		header     http.Header
+		want       time.Duration
+	}{
+		{"no headers", 429, nil, 0},
+		{"retry-after seconds", 503, http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{"retry-after date", 503, http.Header{"Retry-After": {now.Add(5 * time.Second).Format(http.TimeFormat)}}, 5 * time.Second},
+		{"retry-after-ms", 429, http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond},
+		{"rate limit reset", 429, http.Header{"X-Ratelimit-Reset-Requests": {"1s"}, "X-Ratelimit-Reset-Tokens": {"6m0s"}}, 6 * time.Minute},
+		{"rate limit reset ignored on server error", 500, http.Header{"X-Ratelimit-Reset-Requests": {"1s"}}, 0},
+		{"invalid", 429, http.Header{"Retry-After": {"soon"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ServerDelay(tt.statusCode, tt.header, now); got != tt.want {
+				t.Errorf("ServerDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Now()
+	budget := NewBudget(2, 1)
+	budget.now = func() time.Time { return now }
+	budget.last = now
+
+	if !budget.Allow() || !budget.Allow() {
+		t.Fatal("Expected the first two retries to be allowed")
+	}
+	if budget.Allow() {
+		t.Fatal("Expected the budget to be exhausted")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if budget.Available() != 1 {
+		t.Errorf("Expected 1 retry after refill, got %d", budget.Available())
+	}
+
+	now = now.Add(time.Hour)
+	if budget.Available() != 2 {
+		t.Errorf("Expected refill capped at capacity, got %d", budget.Available())
+	}
+
+	var nilBudget *Budget
+	if !nilBudget.Allow() {
+		t.Error("Expected a nil budget to always allow")
+	}
+	if nilBudget.Available() != math.MaxInt {
+		t.Errorf("Expected a nil budget to be unlimited, got %d", nilBudget.Available())
+	}
+}
+
+func TestExecute_BudgetExhausted(t *testing.T) {
+	opts := Options{
+		Config:       Config{MaxRetries: 5},
+		ErrorChecker: func(err error, statusCode int, responseBody []byte) bool { return true },
+		Budget:       NewBudget(1, 0),
+		APIName:      "test",
+	}
+
+	calls := 0
+	_, err := Execute(context.Background(), opts, func(ctx context.Context, attempt int) (any, int, http.Header, []byte, error) {
+		calls++
+		return nil, 503, nil, nil, errors.New("unavailable")
+	})
+
+	var exhaustedErr *RetryExhaustedError
+	if !errors.As(err, &exhaustedErr) || !exhaustedErr.BudgetExhausted {
+		t.Fatalf("Expected budget exhausted error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls (1 retry from the budget), got %d", calls)
+	}
+}
+
+func TestExecute_HonoursRetryAfter(t *testing.T) {
+	opts := Options{
+		Config:       Config{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		ErrorChecker: func(err error, statusCode int, responseBody []byte) bool { return statusCode == 429 },
+	}
+
+	start := time.Now()
+	_, err := Execute(context.Background(), opts, func(ctx context.Context, attempt int) (any, int, http.Header, []byte, error) {
+		if attempt == 0 {
+			return nil, 429, http.Header{"Retry-After-Ms": {"50"}}, nil, errors.New("rate limited")
+		}
+		return "ok", 200, nil, nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected retry to wait for Retry-After, waited %v", elapsed)
+	}
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// InstrumentationName identifies spans created by this module
+const InstrumentationName = "github.com/FrenchMajesty/consistent-classifier"
+
+// Span attribute keys shared across the classifier and adapters
+const (
+	AttrCacheHit   = attribute.Key("classifier.cache_hit")
+	AttrSimilarity = attribute.Key("classifier.similarity")
+	AttrLabel      = attribute.Key("classifier.label")
+	AttrModel      = attribute.Key("llm.model")
+	AttrAPIName    = attribute.Key("retry.api")
+	AttrAttempt    = attribute.Key("retry.attempt")
+	AttrStatusCode = attribute.Key("http.response.status_code")
+)
+
+// Tracer returns the module's tracer from the provider, or a no-op tracer if the provider is nil
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		return noop.NewTracerProvider().Tracer(InstrumentationName)
+	}
+	return tp.Tracer(InstrumentationName)
+}
+
+// Start starts a span, tolerating a nil tracer by returning a non-recording span
+func Start(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if tracer == nil {
+		return ctx, noop.Span{}
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,25 @@
+package classifier
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/logging"
+)
+
+// WithRequestID returns a context carrying the request ID. Log records emitted while classifying
+// with this context include it as "request_id". Classify generates one if the context has none.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return logging.WithRequestID(ctx, requestID)
+}
+
+// RequestID returns the request ID carried by the context, or empty string if none
+func RequestID(ctx context.Context) string {
+	return logging.RequestID(ctx)
+}
+
+// WithRequestCorrelation wraps the logger so records logged with a context include its request ID.
+// Use it for loggers passed directly to custom adapters. If logger is nil, slog.Default() is used.
+func WithRequestCorrelation(logger *slog.Logger) *slog.Logger {
+	return logging.WithRequestCorrelation(logger)
+}
@@ -0,0 +1,86 @@
+package classifier_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+)
+
+func TestClassifier_StructuredLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.UpsertFunc = func(ctx context.Context, id string, vector []float32, metadata map[string]any) error {
+		return errors.New("upsert unavailable")
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+				return "greeting", nil
+			},
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{},
+		Logger:         logger,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	ctx := classifier.WithRequestID(context.Background(), "req-123")
+	if _, err := clf.Classify(ctx, "hello"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if err := clf.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var failure map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		if record["msg"] == "background processing failed" {
+			failure = record
+		}
+	}
+
+	if failure == nil {
+		t.Fatalf("Expected background failure to be logged, got:\n%s", buf.String())
+	}
+	if failure["level"] != "ERROR" {
+		t.Errorf("Expected level ERROR, got %v", failure["level"])
+	}
+	if failure["request_id"] != "req-123" {
+		t.Errorf("Expected request_id 'req-123', got %v", failure["request_id"])
+	}
+	if failure["stage"] != "background" || failure["label"] != "greeting" {
+		t.Errorf("Expected stage and label fields, got %v", failure)
+	}
+	if !strings.Contains(failure["error"].(string), "upsert unavailable") {
+		t.Errorf("Expected wrapped upsert error, got %v", failure["error"])
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := classifier.RequestID(context.Background()); id != "" {
+		t.Errorf("Expected empty request ID, got '%s'", id)
+	}
+
+	ctx := classifier.WithRequestID(context.Background(), "abc")
+	if id := classifier.RequestID(ctx); id != "abc" {
+		t.Errorf("Expected request ID 'abc', got '%s'", id)
+	}
+}
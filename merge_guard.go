@@ -0,0 +1,115 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/clustering"
+)
+
+// DefaultMergeGuardMembers is the number of cluster members checked when MergeGuard.MaxMembers is 0
+const DefaultMergeGuardMembers = 10
+
+// MergeGuard checks a label against several members of the cluster it is about to join, not just
+// its nearest label, so that chains of pairwise-similar labels (A~B, B~C) don't merge unrelated
+// labels (A, C). Checks with a zero threshold are skipped. Rejected merges are logged at warn level
+// and the label starts its own cluster.
+type MergeGuard struct {
+	// MinCentroidSimilarity is the minimum similarity between the label and the mean embedding of the checked members
+	MinCentroidSimilarity float32
+
+	// MaxDiameter is the maximum cosine distance (1 - similarity) between the label and any checked member
+	MaxDiameter float32
+
+	// MinMemberFraction is the minimum fraction of checked members at least MinSimilarityLabel similar to the label
+	MinMemberFraction float32
+
+	// MaxMembers is the number of members checked, most used first. If 0, uses DefaultMergeGuardMembers.
+	MaxMembers int
+}
+
+// checkMerge returns why label shouldn't join the cluster of root, or an empty string if it may.
+// Members without a stored label vector are skipped; a cluster with none left is accepted.
+func (c *Classifier) checkMerge(ctx context.Context, label string, embedding []float32, root string) (string, error) {
+	maxMembers := c.mergeGuard.MaxMembers
+	if maxMembers <= 0 {
+		maxMembers = DefaultMergeGuardMembers
+	}
+
+	// Check the most used members, which define what the cluster means
+	members := c.dsu.Members(root)
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Count > members[j].Count
+	})
+	ids := make([]string, 0, maxMembers)
+	for _, member := range members {
+		if member.Label != label && len(ids) < maxMembers {
+			ids = append(ids, member.Label)
+		}
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	records, err := c.vectorLabel.(ExtendedVectorClient).Fetch(ctx, ids)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch cluster members: %w", err)
+	}
+
+	var centroid []float32
+	checked, similar := 0, 0
+	minSimilarity := float32(1)
+	for _, id := range ids {
+		record, ok := records[id]
+		if !ok || len(record.Vector) == 0 {
+			continue
+		}
+
+		similarity := clustering.CosineSimilarity(embedding, record.Vector)
+		checked++
+		minSimilarity = min(minSimilarity, similarity)
+		if similarity >= c.minSimilarityLabel {
+			similar++
+		}
+
+		if centroid == nil {
+			centroid = make([]float32, len(record.Vector))
+		}
+		for i := range min(len(centroid), len(record.Vector)) {
+			centroid[i] += record.Vector[i]
+		}
+	}
+	if checked == 0 {
+		return "", nil
+	}
+
+	guard := c.mergeGuard
+	if guard.MinCentroidSimilarity > 0 {
+		// The mean's direction is all that matters for cosine similarity, so the sum will do
+		if similarity := clustering.CosineSimilarity(embedding, centroid); similarity < guard.MinCentroidSimilarity {
+			return fmt.Sprintf("centroid similarity %.3f below %.3f", similarity, guard.MinCentroidSimilarity), nil
+		}
+	}
+	if guard.MaxDiameter > 0 && 1-minSimilarity > guard.MaxDiameter {
+		return fmt.Sprintf("distance to farthest member %.3f above %.3f", 1-minSimilarity, guard.MaxDiameter), nil
+	}
+	if guard.MinMemberFraction > 0 {
+		if fraction := float32(similar) / float32(checked); fraction < guard.MinMemberFraction {
+			return fmt.Sprintf("%d of %d members similar, below fraction %.2f", similar, checked, guard.MinMemberFraction), nil
+		}
+	}
+
+	return "", nil
+}
+
+// logRejectedMerge logs a merge rejected by the merge guard for review
+func (c *Classifier) logRejectedMerge(ctx context.Context, label string, root string, score float32, reason string) {
+	c.log().LogAttrs(ctx, slog.LevelWarn, "label merge rejected",
+		slog.String("label", label),
+		slog.String("cluster", root),
+		slog.Float64("similarity", float64(score)),
+		slog.String("reason", reason),
+	)
+}
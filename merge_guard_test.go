@@ -0,0 +1,110 @@
+package classifier_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	classifier "github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestClassifier_MergeGuard(t *testing.T) {
+	vectors := map[string][]float32{
+		"greeting": {1, 0},
+		"hello":    {0.6, 0.8},
+		"hi":       {0.28, 0.96},
+	}
+
+	tests := []struct {
+		name          string
+		guard         classifier.MergeGuard
+		member        bool // Whether hi is already in greeting's cluster
+		expectedRoots int
+	}{
+		{"member fraction rejects", classifier.MergeGuard{MinMemberFraction: 1}, false, 2},
+		{"member fraction accepts", classifier.MergeGuard{MinMemberFraction: 0.5}, false, 1},
+		{"diameter rejects", classifier.MergeGuard{MaxDiameter: 0.5}, false, 2},
+		{"centroid accepts", classifier.MergeGuard{MinCentroidSimilarity: 0.6}, false, 1},
+		{"centroid rejects", classifier.MergeGuard{MinCentroidSimilarity: 0.8}, false, 2},
+		{"members are not checked again", classifier.MergeGuard{MinMemberFraction: 1}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsu := disjoint_set.NewDSU()
+			dsu.Union(dsu.FindOrCreate("greeting"), dsu.FindOrCreate("hello"))
+			if tt.member {
+				dsu.Union(dsu.FindOrCreate("greeting"), dsu.FindOrCreate("hi"))
+			}
+
+			// hi is close to hello but far from greeting, the rest of its cluster
+			ctx := context.Background()
+			vectorLabel := testutil.NewMockVectorClient()
+			vectorLabel.Upsert(ctx, "greeting", vectors["greeting"], map[string]any{"label": "greeting", "root": "greeting"})
+			vectorLabel.Upsert(ctx, "hello", vectors["hello"], map[string]any{"label": "hello", "root": "greeting"})
+			vectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+				return []types.VectorMatch{{ID: "hello", Score: 0.936, Metadata: map[string]any{"root": "greeting"}}}, nil
+			}
+
+			guard := tt.guard
+			var logs bytes.Buffer
+			clf, err := classifier.NewClassifier(classifier.Config{
+				EmbeddingClient: &testutil.MockEmbeddingClient{
+					GenerateEmbeddingFunc: func(ctx context.Context, text string) ([]float32, error) {
+						if vector, ok := vectors[text]; ok {
+							return vector, nil
+						}
+						return []float32{0, 0}, nil
+					},
+				},
+				VectorClientContent: testutil.NewMockVectorClient(),
+				VectorClientLabel:   vectorLabel,
+				LLMClient: &testutil.MockLLMClient{
+					ClassifyFunc: func(ctx context.Context, text string) (string, error) { return "hi", nil },
+				},
+				DSUPersistence: &testutil.MockDSUPersistence{
+					LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+				},
+				MergeGuard: &guard,
+				Logger:     slog.New(slog.NewTextHandler(&logs, nil)),
+			})
+			if err != nil {
+				t.Fatalf("Failed to create classifier: %v", err)
+			}
+			defer clf.Close()
+
+			if _, err := clf.Classify(ctx, "hey there"); err != nil {
+				t.Fatalf("Classify failed: %v", err)
+			}
+
+			if clusters := clf.Clusters(); len(clusters) != tt.expectedRoots {
+				t.Errorf("Expected %d clusters, got %+v", tt.expectedRoots, clusters)
+			}
+			if rejected := strings.Contains(logs.String(), "label merge rejected"); rejected != (tt.expectedRoots == 2) {
+				t.Errorf("Expected a rejected merge to be logged only when rejected, got logs %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestClassifier_MergeGuardRequiresExtendedVectorClient(t *testing.T) {
+	searchOnly := struct{ classifier.VectorClient }{testutil.NewMockVectorClient()}
+
+	_, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   searchOnly,
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		MergeGuard:          &classifier.MergeGuard{MinMemberFraction: 1},
+	})
+	if !errors.Is(err, classifier.ErrUnsupportedVectorClient) {
+		t.Errorf("Expected ErrUnsupportedVectorClient, got %v", err)
+	}
+}
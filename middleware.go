@@ -0,0 +1,303 @@
+package classifier
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/logging"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+// EmbeddingMiddleware wraps an EmbeddingClient to add behaviour such as caching or redaction
+type EmbeddingMiddleware func(EmbeddingClient) EmbeddingClient
+
+// VectorMiddleware wraps a VectorClient to add behaviour such as metrics or rate limiting
+type VectorMiddleware func(VectorClient) VectorClient
+
+// LLMMiddleware wraps an LLMClient to add behaviour such as redaction or timing
+type LLMMiddleware func(LLMClient) LLMClient
+
+// Clients reported in a Call
+const (
+	ClientEmbedding = "embedding"
+	ClientVector    = "vector"
+	ClientLLM       = "llm"
+)
+
+// Call identifies a client method called through an Interceptor, e.g. {Client: "llm", Method: "Classify"}
+type Call struct {
+	Client string
+	Method string
+}
+
+// Interceptor runs around every call made through InterceptEmbedding, InterceptVector or InterceptLLM.
+// It must call next to make the call, and should return its error.
+type Interceptor func(ctx context.Context, call Call, next func(ctx context.Context) error) error
+
+// chain wraps client with middleware, the first being the outermost
+func chain[C any, M ~func(C) C](client C, middleware []M) C {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		client = middleware[i](client)
+	}
+	return client
+}
+
+// Timing returns an interceptor passing the latency and error of every call to observe
+func Timing(observe func(call Call, latency time.Duration, err error)) Interceptor {
+	return func(ctx context.Context, call Call, next func(ctx context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+		observe(call, time.Since(start), err)
+		return err
+	}
+}
+
+// Logging returns an interceptor logging every call at debug level, and failed calls at warn level,
+// with the request ID from the context attached. If logger is nil, slog.Default() is used.
+func Logging(logger *slog.Logger) Interceptor {
+	logger = logging.WithRequestCorrelation(logger)
+	return func(ctx context.Context, call Call, next func(ctx context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+
+		attrs := []slog.Attr{
+			slog.String("client", call.Client),
+			slog.String("method", call.Method),
+			slog.Duration("latency", time.Since(start)),
+		}
+		if err != nil {
+			logger.LogAttrs(ctx, slog.LevelWarn, "client call failed", append(attrs, slog.Any("error", err))...)
+		} else {
+			logger.LogAttrs(ctx, slog.LevelDebug, "client call", attrs...)
+		}
+		return err
+	}
+}
+
+// CallCounter counts calls by client and method. Pass its Intercept method to InterceptEmbedding,
+// InterceptVector or InterceptLLM.
+type CallCounter struct {
+	lock   sync.Mutex
+	counts map[Call]int64
+}
+
+// NewCallCounter creates a CallCounter with no calls counted
+func NewCallCounter() *CallCounter {
+	return &CallCounter{counts: make(map[Call]int64)}
+}
+
+// Intercept implements Interceptor. Failed calls are counted too.
+func (c *CallCounter) Intercept(ctx context.Context, call Call, next func(ctx context.Context) error) error {
+	c.lock.Lock()
+	c.counts[call]++
+	c.lock.Unlock()
+
+	return next(ctx)
+}
+
+// Count returns the number of calls made to the method
+func (c *CallCounter) Count(call Call) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.counts[call]
+}
+
+// Counts returns the number of calls made to every method called so far
+func (c *CallCounter) Counts() map[Call]int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return maps.Clone(c.counts)
+}
+
+// InterceptEmbedding returns middleware running every call through interceptor. The wrapped client
+// implements InputTypeEmbeddingClient and BatchEmbeddingClient only if the original does.
+func InterceptEmbedding(interceptor Interceptor) EmbeddingMiddleware {
+	return func(next EmbeddingClient) EmbeddingClient {
+		client := &interceptedEmbeddingClient{next: next, intercept: interceptor}
+		typed, isTyped := next.(InputTypeEmbeddingClient)
+		batch, isBatch := next.(BatchEmbeddingClient)
+
+		switch {
+		case isTyped && isBatch:
+			return &interceptedFullEmbeddingClient{
+				interceptedInputTypeEmbeddingClient: &interceptedInputTypeEmbeddingClient{client, typed},
+				batch:                               &interceptedBatchEmbeddingClient{client, batch},
+			}
+		case isTyped:
+			return &interceptedInputTypeEmbeddingClient{client, typed}
+		case isBatch:
+			return &interceptedBatchEmbeddingClient{client, batch}
+		default:
+			return client
+		}
+	}
+}
+
+// InterceptVector returns middleware running every call through interceptor. The wrapped client
+// implements ExtendedVectorClient only if the original does.
+func InterceptVector(interceptor Interceptor) VectorMiddleware {
+	return func(next VectorClient) VectorClient {
+		client := &interceptedVectorClient{next: next, intercept: interceptor}
+		if extended, ok := next.(ExtendedVectorClient); ok {
+			return &interceptedExtendedVectorClient{client, extended}
+		}
+		return client
+	}
+}
+
+// InterceptLLM returns middleware running every call through interceptor. The wrapped client
+// implements PromptedLLMClient only if the original does.
+func InterceptLLM(interceptor Interceptor) LLMMiddleware {
+	return func(next LLMClient) LLMClient {
+		client := &interceptedLLMClient{next: next, intercept: interceptor}
+		if prompted, ok := next.(PromptedLLMClient); ok {
+			return &interceptedPromptedLLMClient{client, prompted}
+		}
+		return client
+	}
+}
+
+// interceptedEmbeddingClient runs EmbeddingClient calls through an interceptor
+type interceptedEmbeddingClient struct {
+	next      EmbeddingClient
+	intercept Interceptor
+}
+
+func (c *interceptedEmbeddingClient) GenerateEmbedding(ctx context.Context, text string) (embedding []float32, err error) {
+	err = c.intercept(ctx, Call{Client: ClientEmbedding, Method: "GenerateEmbedding"}, func(ctx context.Context) error {
+		embedding, err = c.next.GenerateEmbedding(ctx, text)
+		return err
+	})
+	return embedding, err
+}
+
+// interceptedInputTypeEmbeddingClient adds InputTypeEmbeddingClient to interceptedEmbeddingClient
+type interceptedInputTypeEmbeddingClient struct {
+	*interceptedEmbeddingClient
+	typed InputTypeEmbeddingClient
+}
+
+func (c *interceptedInputTypeEmbeddingClient) GenerateEmbeddingForInput(ctx context.Context, text string, inputType types.EmbeddingInputType) (embedding []float32, err error) {
+	err = c.intercept(ctx, Call{Client: ClientEmbedding, Method: "GenerateEmbeddingForInput"}, func(ctx context.Context) error {
+		embedding, err = c.typed.GenerateEmbeddingForInput(ctx, text, inputType)
+		return err
+	})
+	return embedding, err
+}
+
+// interceptedBatchEmbeddingClient adds BatchEmbeddingClient to interceptedEmbeddingClient
+type interceptedBatchEmbeddingClient struct {
+	*interceptedEmbeddingClient
+	batch BatchEmbeddingClient
+}
+
+func (c *interceptedBatchEmbeddingClient) GenerateEmbeddings(ctx context.Context, texts []string, inputType types.EmbeddingInputType) (embeddings [][]float32, err error) {
+	err = c.intercept(ctx, Call{Client: ClientEmbedding, Method: "GenerateEmbeddings"}, func(ctx context.Context) error {
+		embeddings, err = c.batch.GenerateEmbeddings(ctx, texts, inputType)
+		return err
+	})
+	return embeddings, err
+}
+
+// interceptedFullEmbeddingClient implements both InputTypeEmbeddingClient and BatchEmbeddingClient
+type interceptedFullEmbeddingClient struct {
+	*interceptedInputTypeEmbeddingClient
+	batch *interceptedBatchEmbeddingClient
+}
+
+func (c *interceptedFullEmbeddingClient) GenerateEmbeddings(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error) {
+	return c.batch.GenerateEmbeddings(ctx, texts, inputType)
+}
+
+// interceptedVectorClient runs VectorClient calls through an interceptor
+type interceptedVectorClient struct {
+	next      VectorClient
+	intercept Interceptor
+}
+
+func (c *interceptedVectorClient) Search(ctx context.Context, vector []float32, topK int) (matches []types.VectorMatch, err error) {
+	err = c.intercept(ctx, Call{Client: ClientVector, Method: "Search"}, func(ctx context.Context) error {
+		matches, err = c.next.Search(ctx, vector, topK)
+		return err
+	})
+	return matches, err
+}
+
+func (c *interceptedVectorClient) Upsert(ctx context.Context, id string, vector []float32, metadata map[string]any) error {
+	return c.intercept(ctx, Call{Client: ClientVector, Method: "Upsert"}, func(ctx context.Context) error {
+		return c.next.Upsert(ctx, id, vector, metadata)
+	})
+}
+
+// interceptedExtendedVectorClient adds ExtendedVectorClient to interceptedVectorClient
+type interceptedExtendedVectorClient struct {
+	*interceptedVectorClient
+	extended ExtendedVectorClient
+}
+
+func (c *interceptedExtendedVectorClient) SearchWithFilter(ctx context.Context, vector []float32, topK int, filter map[string]any) (matches []types.VectorMatch, err error) {
+	err = c.intercept(ctx, Call{Client: ClientVector, Method: "SearchWithFilter"}, func(ctx context.Context) error {
+		matches, err = c.extended.SearchWithFilter(ctx, vector, topK, filter)
+		return err
+	})
+	return matches, err
+}
+
+func (c *interceptedExtendedVectorClient) Fetch(ctx context.Context, ids []string) (records map[string]types.VectorRecord, err error) {
+	err = c.intercept(ctx, Call{Client: ClientVector, Method: "Fetch"}, func(ctx context.Context) error {
+		records, err = c.extended.Fetch(ctx, ids)
+		return err
+	})
+	return records, err
+}
+
+func (c *interceptedExtendedVectorClient) UpdateMetadata(ctx context.Context, id string, metadata map[string]any) error {
+	return c.intercept(ctx, Call{Client: ClientVector, Method: "UpdateMetadata"}, func(ctx context.Context) error {
+		return c.extended.UpdateMetadata(ctx, id, metadata)
+	})
+}
+
+func (c *interceptedExtendedVectorClient) Delete(ctx context.Context, ids []string) error {
+	return c.intercept(ctx, Call{Client: ClientVector, Method: "Delete"}, func(ctx context.Context) error {
+		return c.extended.Delete(ctx, ids)
+	})
+}
+
+func (c *interceptedExtendedVectorClient) BatchUpsert(ctx context.Context, records []types.VectorRecord) error {
+	return c.intercept(ctx, Call{Client: ClientVector, Method: "BatchUpsert"}, func(ctx context.Context) error {
+		return c.extended.BatchUpsert(ctx, records)
+	})
+}
+
+// interceptedLLMClient runs LLMClient calls through an interceptor
+type interceptedLLMClient struct {
+	next      LLMClient
+	intercept Interceptor
+}
+
+func (c *interceptedLLMClient) Classify(ctx context.Context, text string) (label string, err error) {
+	err = c.intercept(ctx, Call{Client: ClientLLM, Method: "Classify"}, func(ctx context.Context) error {
+		label, err = c.next.Classify(ctx, text)
+		return err
+	})
+	return label, err
+}
+
+// interceptedPromptedLLMClient adds PromptedLLMClient to interceptedLLMClient
+type interceptedPromptedLLMClient struct {
+	*interceptedLLMClient
+	prompted PromptedLLMClient
+}
+
+func (c *interceptedPromptedLLMClient) ClassifyWithPrompt(ctx context.Context, data types.PromptData) (label string, err error) {
+	err = c.intercept(ctx, Call{Client: ClientLLM, Method: "ClassifyWithPrompt"}, func(ctx context.Context) error {
+		label, err = c.prompted.ClassifyWithPrompt(ctx, data)
+		return err
+	})
+	return label, err
+}
@@ -0,0 +1,148 @@
+package classifier_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	classifier "github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+// typedBatchEmbeddingClient implements both InputTypeEmbeddingClient and BatchEmbeddingClient
+type typedBatchEmbeddingClient struct {
+	*testutil.MockBatchEmbeddingClient
+}
+
+func (c typedBatchEmbeddingClient) GenerateEmbeddingForInput(ctx context.Context, text string, inputType types.EmbeddingInputType) ([]float32, error) {
+	return c.GenerateEmbedding(ctx, text)
+}
+
+func TestIntercept_PreservesOptionalInterfaces(t *testing.T) {
+	counter := classifier.NewCallCounter()
+
+	embeddingTests := []struct {
+		name         string
+		client       classifier.EmbeddingClient
+		typed, batch bool
+	}{
+		{"plain", &testutil.MockEmbeddingClient{}, false, false},
+		{"input type", &testutil.MockInputTypeEmbeddingClient{}, true, false},
+		{"batch", &testutil.MockBatchEmbeddingClient{}, false, true},
+		{"input type and batch", typedBatchEmbeddingClient{&testutil.MockBatchEmbeddingClient{}}, true, true},
+	}
+	for _, tt := range embeddingTests {
+		wrapped := classifier.InterceptEmbedding(counter.Intercept)(tt.client)
+		if _, ok := wrapped.(classifier.InputTypeEmbeddingClient); ok != tt.typed {
+			t.Errorf("%s: expected InputTypeEmbeddingClient %v, got %v", tt.name, tt.typed, ok)
+		}
+		if _, ok := wrapped.(classifier.BatchEmbeddingClient); ok != tt.batch {
+			t.Errorf("%s: expected BatchEmbeddingClient %v, got %v", tt.name, tt.batch, ok)
+		}
+	}
+
+	searchOnly := struct{ classifier.VectorClient }{testutil.NewMockVectorClient()}
+	if _, ok := classifier.InterceptVector(counter.Intercept)(searchOnly).(classifier.ExtendedVectorClient); ok {
+		t.Error("Expected a basic vector client to stay basic")
+	}
+	if _, ok := classifier.InterceptVector(counter.Intercept)(testutil.NewMockVectorClient()).(classifier.ExtendedVectorClient); !ok {
+		t.Error("Expected an extended vector client to stay extended")
+	}
+
+	classifyOnly := struct{ classifier.LLMClient }{&testutil.MockLLMClient{}}
+	if _, ok := classifier.InterceptLLM(counter.Intercept)(classifyOnly).(classifier.PromptedLLMClient); ok {
+		t.Error("Expected a basic LLM client to stay basic")
+	}
+	if _, ok := classifier.InterceptLLM(counter.Intercept)(&testutil.MockLLMClient{}).(classifier.PromptedLLMClient); !ok {
+		t.Error("Expected a prompted LLM client to stay prompted")
+	}
+}
+
+func TestClassifier_Middleware(t *testing.T) {
+	var lock sync.Mutex
+	order := make([]string, 0)
+	trace := func(name string) classifier.LLMMiddleware {
+		return classifier.InterceptLLM(func(ctx context.Context, call classifier.Call, next func(ctx context.Context) error) error {
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+			return next(ctx)
+		})
+	}
+	counter := classifier.NewCallCounter()
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		Tenant:              "acme", // Requires the content client to stay extended
+		EmbeddingMiddleware: []classifier.EmbeddingMiddleware{classifier.InterceptEmbedding(counter.Intercept)},
+		VectorMiddleware:    []classifier.VectorMiddleware{classifier.InterceptVector(counter.Intercept)},
+		LLMMiddleware:       []classifier.LLMMiddleware{trace("outer"), classifier.InterceptLLM(counter.Intercept), trace("inner")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	if _, err := clf.Classify(context.Background(), "hello"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	if !slices.Equal(order, []string{"outer", "inner"}) {
+		t.Errorf("Expected the first middleware to be outermost, got %v", order)
+	}
+	expected := map[classifier.Call]int64{
+		{Client: classifier.ClientLLM, Method: "Classify"}:                1,
+		{Client: classifier.ClientVector, Method: "SearchWithFilter"}:     1,
+		{Client: classifier.ClientVector, Method: "Search"}:               1,
+		{Client: classifier.ClientVector, Method: "Upsert"}:               2,
+		{Client: classifier.ClientEmbedding, Method: "GenerateEmbedding"}: 3,
+	}
+	for call, count := range expected {
+		if got := counter.Count(call); got != count {
+			t.Errorf("Expected %d calls to %s.%s, got %d", count, call.Client, call.Method, got)
+		}
+	}
+}
+
+func TestTimingAndLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	var latencies []time.Duration
+	timing := classifier.Timing(func(call classifier.Call, latency time.Duration, err error) {
+		latencies = append(latencies, latency)
+	})
+
+	failing := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			time.Sleep(time.Millisecond)
+			return "", errors.New("model overloaded")
+		},
+	}
+	client := classifier.InterceptLLM(timing)(classifier.InterceptLLM(classifier.Logging(logger))(failing))
+
+	ctx := classifier.WithRequestID(context.Background(), "req-1")
+	if _, err := client.Classify(ctx, "hello"); err == nil {
+		t.Fatal("Expected the error to pass through the middleware")
+	}
+
+	if len(latencies) != 1 || latencies[0] < time.Millisecond {
+		t.Errorf("Expected one latency of at least 1ms, got %v", latencies)
+	}
+	for _, want := range []string{"level=WARN", "client=llm", "method=Classify", "model overloaded", "request_id=req-1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected log to contain %q, got %s", want, buf.String())
+		}
+	}
+}
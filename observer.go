@@ -0,0 +1,46 @@
+package classifier
+
+import "context"
+
+// Observer receives classification lifecycle events, e.g. to stream them to an analytics pipeline or
+// alert on new clusters. Callbacks run synchronously on the classifying goroutine, so they must be
+// fast and safe for concurrent use: hand events off to a queue rather than publishing inline.
+type Observer interface {
+	// OnCacheHit is called when a cached text answers a classification with the returned label
+	OnCacheHit(ctx context.Context, text string, label string, score float32)
+
+	// OnCacheMiss is called when no cached text is similar enough, before the LLM is called
+	OnCacheMiss(ctx context.Context, text string)
+
+	// OnLLMLabel is called with the label the LLM generated for a text
+	OnLLMLabel(ctx context.Context, text string, label string)
+
+	// OnLabelMerged is called when a label joins the cluster of another label with the given similarity
+	OnLabelMerged(ctx context.Context, from string, to string, score float32)
+
+	// OnNewCluster is called when a label the classifier had never seen starts its own cluster. It is
+	// called once per label, and not at all if the label's background clustering failed.
+	OnNewCluster(ctx context.Context, label string)
+
+	// OnBackgroundError is called when background work fails. The label is that of the classification
+	// whose background tasks failed, or empty for work not tied to one, such as a scheduled Reconcile.
+	OnBackgroundError(ctx context.Context, label string, err error)
+}
+
+// NopObserver ignores all events. Embed it to implement only part of Observer.
+type NopObserver struct{}
+
+func (NopObserver) OnCacheHit(ctx context.Context, text string, label string, score float32) {}
+func (NopObserver) OnCacheMiss(ctx context.Context, text string)                             {}
+func (NopObserver) OnLLMLabel(ctx context.Context, text string, label string)                {}
+func (NopObserver) OnLabelMerged(ctx context.Context, from string, to string, score float32) {}
+func (NopObserver) OnNewCluster(ctx context.Context, label string)                           {}
+func (NopObserver) OnBackgroundError(ctx context.Context, label string, err error)           {}
+
+// observe returns the configured observer, or a no-op observer if none is set
+func (c *Classifier) observe() Observer {
+	if c.observer == nil {
+		return NopObserver{}
+	}
+	return c.observer
+}
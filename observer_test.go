@@ -0,0 +1,147 @@
+package classifier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	classifier "github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestClassifier_Observer(t *testing.T) {
+	// "refund" starts a cluster, "money back" joins it and "give me my money" hits the cache
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		if vector[0] == float32(len("give me my money"))/100 {
+			return []types.VectorMatch{{ID: "1", Score: 0.97, Metadata: map[string]any{"label": "money_back"}}}, nil
+		}
+		return nil, nil
+	}
+	mockVectorLabel := testutil.NewMockVectorClient()
+	mockVectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		if vector[0] == float32(len("money_back"))/100 {
+			return []types.VectorMatch{{ID: "refund", Score: 0.9, Metadata: map[string]any{"root": "refund"}}}, nil
+		}
+		return nil, nil
+	}
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			if text == "money back" {
+				return "money_back", nil
+			}
+			return "refund", nil
+		},
+	}
+	observer := &testutil.MockObserver{}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   mockVectorLabel,
+		LLMClient:           mockLLM,
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		Observer:            observer,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	ctx := context.Background()
+	for _, text := range []string{"refund", "money back", "give me my money"} {
+		if _, err := clf.Classify(ctx, text); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+	}
+
+	expected := []string{
+		"miss refund",
+		"llm refund refund",
+		"new_cluster refund",
+		"miss money back",
+		"llm money back money_back",
+		"merged money_back refund 0.90",
+		"hit give me my money refund 0.97",
+	}
+	if events := observer.Recorded(); !slices.Equal(events, expected) {
+		t.Errorf("Expected events %q, got %q", expected, events)
+	}
+}
+
+func TestClassifier_ObserverBackgroundError(t *testing.T) {
+	mockVectorLabel := testutil.NewMockVectorClient()
+	mockVectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		return nil, errors.New("label index unavailable")
+	}
+	observer := &testutil.MockObserver{}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   mockVectorLabel,
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		Observer:            observer,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	if _, err := clf.Classify(context.Background(), "hello"); err != nil {
+		t.Fatalf("Expected background errors not to fail Classify, got %v", err)
+	}
+	events := observer.Recorded()
+	if !slices.Contains(events, "background_error short_text") {
+		t.Errorf("Expected a background error for short_text, got %q", events)
+	}
+	if slices.Contains(events, "new_cluster short_text") {
+		t.Errorf("Expected no new cluster when clustering failed, got %q", events)
+	}
+}
+
+func TestClassifier_ObserverNewClusterOnce(t *testing.T) {
+	observer := &testutil.MockObserver{}
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) { return "refund", nil },
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{},
+		Observer:       observer,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	// Concurrent misses generating the same new label announce its cluster once
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := clf.Classify(context.Background(), fmt.Sprintf("refund request %d", i)); err != nil {
+				t.Errorf("Classify failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, event := range observer.Recorded() {
+		if event == "new_cluster refund" {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected one new cluster event, got %d", created)
+	}
+}
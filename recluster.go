@@ -0,0 +1,254 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/clustering"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+)
+
+// Linkage selects how Recluster measures the similarity between two clusters of labels
+type Linkage = clustering.Linkage
+
+const (
+	// LinkageAverage uses the mean similarity over all pairs of labels in the two clusters
+	LinkageAverage = clustering.LinkageAverage
+
+	// LinkageComplete uses the least similar pair of labels, so every label in a cluster is
+	// similar to every other. It prevents chains of loosely related labels from merging.
+	LinkageComplete = clustering.LinkageComplete
+)
+
+// ReclusterOptions controls a Recluster run
+type ReclusterOptions struct {
+	// Threshold is the linkage similarity at which clusters merge. If 0, uses the classifier's MinSimilarityLabel.
+	Threshold float32
+
+	// Linkage selects how cluster similarity is computed. Defaults to LinkageAverage.
+	Linkage Linkage
+
+	// BatchSize is the number of label vectors fetched per request. If 0, uses DefaultReconcileBatchSize.
+	BatchSize int
+
+	// Apply replaces the classifier's clusters with the result. Otherwise the result is only a preview.
+	Apply bool
+}
+
+// ReclusterResult is the clustering produced by Recluster and how it differs from the current one
+type ReclusterResult struct {
+	// DSU holds the new clusters. Parent categories, label usage and merge history are carried over from
+	// the current clusters; canonical labels are chosen when the result is applied. Each moved label with
+	// a stored vector that joined another label's cluster records a merge into its new root, scored by
+	// its linkage similarity to the rest of the cluster.
+	DSU *disjoint_set.DSU
+
+	// Moved lists the labels whose root differs from their current root
+	Moved []RootChange
+
+	// ClustersBefore and ClustersAfter are the number of clusters in the current and new DSU
+	ClustersBefore int
+	ClustersAfter  int
+
+	// Missing lists labels with no stored label vector. They join the new cluster of their current root.
+	Missing []string
+
+	// Applied reports whether the classifier now uses the new clusters
+	Applied bool
+}
+
+// Recluster rebuilds every label cluster from scratch with threshold-based agglomerative clustering
+// of the stored label embeddings. Unlike online merging, which compares each new label with its
+// single nearest label as it arrives, the result doesn't depend on arrival order.
+//
+// Each new cluster keeps the current root shared by most of its members, so names stay stable.
+// When applied, moved labels are flagged for the next Reconcile; call SaveDSU to persist the result.
+// It is safe to run alongside Classify: labels created or merged while it runs keep their clusters
+// when the result is applied, and usage recorded meanwhile is kept.
+// Requires the label VectorClient to implement ExtendedVectorClient. Memory is quadratic in the number of labels.
+func (c *Classifier) Recluster(ctx context.Context, opts ReclusterOptions) (*ReclusterResult, error) {
+	vectorLabel, ok := c.vectorLabel.(ExtendedVectorClient)
+	if !ok {
+		return nil, fmt.Errorf("recluster: %w", ErrUnsupportedVectorClient)
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = c.minSimilarityLabel
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultReconcileBatchSize
+	}
+
+	// Work from a snapshot, so classifications made meanwhile can be told apart when applying
+	base := c.dsu.Clone()
+	labels := base.Labels()
+	slices.Sort(labels)
+
+	result := &ReclusterResult{DSU: disjoint_set.NewDSU(), ClustersBefore: base.CountSets()}
+	embedded := make([]string, 0, len(labels))
+	vectors := make([][]float32, 0, len(labels))
+	for batch := range slices.Chunk(labels, opts.BatchSize) {
+		records, err := vectorLabel.Fetch(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("recluster: failed to fetch label vectors: %w", err)
+		}
+		for _, label := range batch {
+			record, ok := records[label]
+			if !ok || len(record.Vector) == 0 {
+				result.Missing = append(result.Missing, label)
+				continue
+			}
+			embedded = append(embedded, label)
+			vectors = append(vectors, record.Vector)
+		}
+	}
+
+	scores := make(map[string]float32) // Linkage similarity of each label to the rest of its new cluster
+	for _, cluster := range clustering.Agglomerative(vectors, opts.Threshold, opts.Linkage) {
+		members := make([]string, len(cluster))
+		for i, idx := range cluster {
+			members[i] = embedded[idx]
+		}
+		c.addReclustered(base, result.DSU, members)
+
+		for i, idx := range cluster {
+			others := make([][]float32, 0, len(cluster)-1)
+			for j, other := range cluster {
+				if j != i {
+					others = append(others, vectors[other])
+				}
+			}
+			scores[embedded[idx]] = opts.Linkage.Similarity([][]float32{vectors[idx]}, others)
+		}
+	}
+	for _, label := range result.Missing {
+		keepCurrentRoot(base, result.DSU, label)
+	}
+
+	for _, label := range labels {
+		oldRoot, _ := base.Root(label)
+		newRoot, _ := result.DSU.Root(label)
+		if oldRoot == newRoot {
+			continue
+		}
+		result.Moved = append(result.Moved, RootChange{Label: label, OldRoot: oldRoot, NewRoot: newRoot})
+		if score, ok := scores[label]; ok && newRoot != label {
+			result.DSU.RecordMerge(label, newRoot, score)
+		}
+	}
+	result.ClustersAfter = result.DSU.CountSets()
+
+	if opts.Apply {
+		if err := c.applyRecluster(ctx, base, result); err != nil {
+			return result, fmt.Errorf("recluster: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// addReclustered adds a new cluster to dsu, rooted at the root in current shared by most members.
+// Ties, and clusters containing no current root, go to the alphabetically first candidate.
+func (c *Classifier) addReclustered(current *disjoint_set.DSU, dsu *disjoint_set.DSU, members []string) {
+	votes := make(map[string]int)
+	for _, member := range members {
+		if root, ok := current.Root(member); ok && slices.Contains(members, root) {
+			votes[root]++
+		}
+	}
+
+	root := members[0]
+	for _, member := range members {
+		if votes[member] > votes[root] {
+			root = member
+		}
+	}
+
+	// Union by rank keeps the first label added as the root of a cluster of singletons
+	rootIdx := dsu.FindOrCreate(root)
+	for _, member := range members {
+		if member != root {
+			dsu.Union(rootIdx, dsu.FindOrCreate(member))
+		}
+		copyStats(current, dsu, member)
+	}
+
+	parent := c.taxonomy[root]
+	if parent == "" {
+		parent = current.Parent(root)
+	}
+	if parent != "" {
+		dsu.SetParent(root, parent)
+	}
+}
+
+// keepCurrentRoot adds label to next in the cluster of its root in current
+func keepCurrentRoot(current *disjoint_set.DSU, next *disjoint_set.DSU, label string) {
+	defer copyStats(current, next, label)
+
+	root, ok := current.Root(label)
+	if !ok || root == label {
+		next.FindOrCreate(label)
+		return
+	}
+	next.Union(next.FindOrCreate(root), next.FindOrCreate(label))
+}
+
+// copyStats carries the label's usage statistics, origin and merge history over from current to next
+func copyStats(current *disjoint_set.DSU, next *disjoint_set.DSU, label string) {
+	if stats, ok := current.Stats(label); ok {
+		next.SetStats(stats)
+	}
+}
+
+// applyRecluster replaces the classifier's clusters with the result's, built from base, then chooses
+// their canonical labels. Classifications made since base was taken are merged into the result under
+// the DSU's write lock, so none is lost to the swap.
+func (c *Classifier) applyRecluster(ctx context.Context, base *disjoint_set.DSU, result *ReclusterResult) error {
+	moved := make([]string, len(result.Moved))
+	for i, change := range result.Moved {
+		moved[i] = change.Label
+	}
+
+	c.dsu.ReplaceMerged(result.DSU, func(current *disjoint_set.DSU, next *disjoint_set.DSU) {
+		mergeConcurrentChanges(base, current, next)
+		next.MarkChanged(moved...)
+		next.MarkChanged(current.TakeChanged()...)
+	})
+
+	result.Applied = true
+	c.metricsRecorder().RecordLabelSets(c.dsu.Size(), c.dsu.CountSets())
+
+	return c.RefreshCanonicalLabels(ctx)
+}
+
+// mergeConcurrentChanges carries the changes made from base to current over to next, the copy of the
+// result built from base that is about to replace current. Labels removed meanwhile are removed, labels
+// created meanwhile keep their current root, and labels merged meanwhile join their current root, so
+// the merge isn't undone. Usage is taken from current.
+func mergeConcurrentChanges(base *disjoint_set.DSU, current *disjoint_set.DSU, next *disjoint_set.DSU) {
+	for _, label := range base.Labels() {
+		if !current.Contains(label) {
+			next.Remove(label)
+		}
+	}
+
+	for _, label := range current.Labels() {
+		if !base.Contains(label) {
+			keepCurrentRoot(current, next, label)
+			continue
+		}
+
+		// Merge history made meanwhile follows the merges the recluster recorded
+		stats, _ := current.Stats(label)
+		before, _ := base.Stats(label)
+		reclustered, _ := next.Stats(label)
+		if len(stats.Merges) > len(before.Merges) {
+			next.Union(next.FindOrCreate(stats.Root), next.FindOrCreate(label))
+			stats.Merges = append(reclustered.Merges, stats.Merges[len(before.Merges):]...)
+		} else {
+			stats.Merges = reclustered.Merges
+		}
+		next.SetStats(stats)
+	}
+}
@@ -0,0 +1,196 @@
+package classifier_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestClassifier_Recluster(t *testing.T) {
+	// Online merging chained refund into the greeting cluster through hello_refund
+	dsu := disjoint_set.NewDSU()
+	greeting := dsu.FindOrCreate("greeting")
+	for _, label := range []string{"hello", "hello_refund", "refund", "orphan"} {
+		dsu.Union(greeting, dsu.FindOrCreate(label))
+	}
+	dsu.SetParent("greeting", "small_talk")
+
+	vectorLabel := testutil.NewMockVectorClient()
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"greeting":     {1, 0},
+		"hello":        {0.99, 0.14},
+		"hello_refund": {0.71, 0.71},
+		"refund":       {0, 1},
+	}
+	for label, vector := range vectors {
+		vectorLabel.Upsert(ctx, label, vector, map[string]any{"label": label, "root": "greeting"})
+	}
+
+	clf := newReconcileClassifier(t, dsu, vectorLabel)
+
+	result, err := clf.Recluster(ctx, classifier.ReclusterOptions{Threshold: 0.9, Linkage: classifier.LinkageComplete})
+	if err != nil {
+		t.Fatalf("Recluster failed: %v", err)
+	}
+	if result.Applied || result.ClustersBefore != 1 || result.ClustersAfter != 3 {
+		t.Errorf("Expected a preview splitting 1 cluster into 3, got %+v", result)
+	}
+	expected := []classifier.RootChange{
+		{Label: "hello_refund", OldRoot: "greeting", NewRoot: "hello_refund"},
+		{Label: "refund", OldRoot: "greeting", NewRoot: "refund"},
+	}
+	if !reflect.DeepEqual(result.Moved, expected) {
+		t.Errorf("Expected moves %v, got %v", expected, result.Moved)
+	}
+	if !reflect.DeepEqual(result.Missing, []string{"orphan"}) {
+		t.Errorf("Expected orphan to be missing, got %v", result.Missing)
+	}
+	if root, _ := result.DSU.Root("orphan"); root != "greeting" {
+		t.Errorf("Expected orphan to stay with its current root, got %s", root)
+	}
+	if result.DSU.Parent("greeting") != "small_talk" {
+		t.Errorf("Expected the parent category to carry over, got %q", result.DSU.Parent("greeting"))
+	}
+	if clf.GetMetrics().ConvergedLabels != 1 {
+		t.Error("Expected a preview not to change the classifier's clusters")
+	}
+
+	// Applying uses the new clusters and flags the moved labels for the next Reconcile
+	result, err = clf.Recluster(ctx, classifier.ReclusterOptions{Threshold: 0.9, Linkage: classifier.LinkageComplete, Apply: true})
+	if err != nil {
+		t.Fatalf("Recluster failed: %v", err)
+	}
+	if !result.Applied || clf.GetMetrics().ConvergedLabels != 3 {
+		t.Errorf("Expected 3 clusters after applying, got %d", clf.GetMetrics().ConvergedLabels)
+	}
+	if _, err := clf.Reconcile(ctx, classifier.ReconcileOptions{}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if root := vectorLabel.Storage["refund"].Metadata["root"]; root != "refund" {
+		t.Errorf("Expected refund's label vector to be re-rooted, got %v", root)
+	}
+}
+
+func TestClassifier_ReclusterRecordsMerges(t *testing.T) {
+	// money_back was merged into greeting by mistake, but belongs with refund
+	dsu := disjoint_set.NewDSU()
+	dsu.MergeLabels("money_back", "greeting", 0.86)
+	dsu.FindOrCreate("refund")
+
+	vectorLabel := testutil.NewMockVectorClient()
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"greeting":   {1, 0},
+		"money_back": {0.14, 0.99},
+		"refund":     {0, 1},
+	}
+	for label, vector := range vectors {
+		vectorLabel.Upsert(ctx, label, vector, map[string]any{"label": label})
+	}
+
+	clf := newReconcileClassifier(t, dsu, vectorLabel)
+
+	result, err := clf.Recluster(ctx, classifier.ReclusterOptions{Threshold: 0.9})
+	if err != nil {
+		t.Fatalf("Recluster failed: %v", err)
+	}
+	if root, _ := result.DSU.Root("money_back"); root != "refund" {
+		t.Fatalf("Expected money_back to move to refund, got %s", root)
+	}
+
+	stats, _ := result.DSU.Stats("money_back")
+	if len(stats.Merges) != 2 || stats.Merges[0].Into != "greeting" {
+		t.Fatalf("Expected the old merge to be kept and a new one recorded, got %+v", stats.Merges)
+	}
+	if merge := stats.Merges[1]; merge.Into != "refund" || merge.Score < 0.98 || merge.Score > 1 {
+		t.Errorf("Expected a merge into refund scored by its similarity, got %+v", merge)
+	}
+	if stats, _ := result.DSU.Stats("refund"); len(stats.Merges) != 0 {
+		t.Errorf("Expected the root not to record a merge, got %+v", stats.Merges)
+	}
+}
+
+// fetchHookVectorClient runs onFetch once, on the first Fetch
+type fetchHookVectorClient struct {
+	*testutil.MockVectorClient
+	onFetch func()
+}
+
+func (c *fetchHookVectorClient) Fetch(ctx context.Context, ids []string) (map[string]types.VectorRecord, error) {
+	if c.onFetch != nil {
+		c.onFetch()
+		c.onFetch = nil
+	}
+	return c.MockVectorClient.Fetch(ctx, ids)
+}
+
+func TestClassifier_ReclusterKeepsConcurrentChanges(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	for _, label := range []string{"greeting", "hello", "refund", "obsolete"} {
+		dsu.FindOrCreate(label)
+	}
+
+	vectorLabel := &fetchHookVectorClient{MockVectorClient: testutil.NewMockVectorClient()}
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"greeting": {1, 0},
+		"hello":    {0.99, 0.14},
+		"refund":   {0, 1},
+		"obsolete": {0.71, -0.71},
+	}
+	for label, vector := range vectors {
+		vectorLabel.Upsert(ctx, label, vector, map[string]any{"label": label})
+	}
+
+	// Classifications keep changing the DSU while the label vectors are fetched
+	vectorLabel.onFetch = func() {
+		dsu.Observe("hello")
+		dsu.MergeLabels("late", "greeting", 0.9)
+		dsu.MergeLabels("refund", "greeting", 0.85)
+		dsu.Remove("obsolete")
+	}
+
+	clf := newReconcileClassifier(t, dsu, vectorLabel)
+
+	if _, err := clf.Recluster(ctx, classifier.ReclusterOptions{Threshold: 0.9, Apply: true}); err != nil {
+		t.Fatalf("Recluster failed: %v", err)
+	}
+
+	for _, label := range []string{"hello", "late", "refund"} {
+		if root, _ := dsu.Root(label); root != "greeting" {
+			t.Errorf("Expected %s to be rooted at greeting, got %q", label, root)
+		}
+	}
+	if usage, _ := dsu.Usage("hello"); usage.Count != 1 {
+		t.Errorf("Expected the usage counted meanwhile to be kept, got %+v", usage)
+	}
+	if stats, _ := dsu.Stats("hello"); len(stats.Merges) != 1 || stats.Merges[0].Into != "greeting" {
+		t.Errorf("Expected the recluster merge to be recorded, got %+v", stats.Merges)
+	}
+	if stats, _ := dsu.Stats("refund"); len(stats.Merges) != 1 || stats.Merges[0].Score != 0.85 {
+		t.Errorf("Expected the merge made meanwhile to be kept, got %+v", stats.Merges)
+	}
+	if dsu.Contains("obsolete") {
+		t.Error("Expected the label removed meanwhile to stay removed")
+	}
+	if changed := dsu.TakeChanged(); !slices.Contains(changed, "refund") || !slices.Contains(changed, "hello") {
+		t.Errorf("Expected moved and merged labels to be flagged for Reconcile, got %v", changed)
+	}
+}
+
+func TestClassifier_ReclusterUnsupportedVectorClient(t *testing.T) {
+	searchOnly := struct{ classifier.VectorClient }{testutil.NewMockVectorClient()}
+	clf := newReconcileClassifier(t, disjoint_set.NewDSU(), searchOnly)
+
+	if _, err := clf.Recluster(context.Background(), classifier.ReclusterOptions{}); !errors.Is(err, classifier.ErrUnsupportedVectorClient) {
+		t.Errorf("Expected ErrUnsupportedVectorClient, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// DefaultReconcileBatchSize is the number of label vectors fetched per request when ReconcileOptions.BatchSize is 0
+const DefaultReconcileBatchSize = 100
+
+// ReconcileOptions controls a Reconcile run
+type ReconcileOptions struct {
+	// BatchSize is the number of label vectors fetched per request. If 0, uses DefaultReconcileBatchSize.
+	BatchSize int
+
+	// Full checks every label. Otherwise only the labels in clusters merged since the last run are
+	// checked. The first run of a classifier is always full, since merges made before it started are unknown.
+	Full bool
+}
+
+// ReconcileReport describes the label vectors changed by a Reconcile run
+type ReconcileReport struct {
+	// Full reports whether every label was checked
+	Full bool
+
+	// Checked is the number of labels compared against their stored label vector
+	Checked int
+
+	// Updated lists the label vectors whose root metadata was rewritten
+	Updated []RootChange
+
+	// Missing lists labels with no stored label vector, e.g. because caching it failed.
+	// They are checked again on the next run.
+	Missing []string
+}
+
+// RootChange is a label whose root changed
+type RootChange struct {
+	Label   string
+	OldRoot string
+	NewRoot string
+}
+
+// Reconcile rewrites the root metadata of stored label vectors that no longer match the DSU.
+// Label merges only update the DSU, so label vectors keep pointing at the root they had when
+// they were cached, and label-similarity lookups chain through outdated roots until reconciled.
+// Requires the label VectorClient to implement ExtendedVectorClient. Safe to call while classifying;
+// concurrent Reconcile calls run one at a time.
+func (c *Classifier) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileReport, error) {
+	vectorLabel, ok := c.vectorLabel.(ExtendedVectorClient)
+	if !ok {
+		return nil, fmt.Errorf("reconcile: %w", ErrUnsupportedVectorClient)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultReconcileBatchSize
+	}
+
+	c.reconcileLock.Lock()
+	defer c.reconcileLock.Unlock()
+
+	report := &ReconcileReport{Full: opts.Full || !c.reconciled}
+	labels := c.dsu.TakeChanged()
+	if report.Full {
+		labels = c.dsu.Labels()
+		slices.Sort(labels)
+	}
+
+	for i := 0; i < len(labels); i += opts.BatchSize {
+		batch := labels[i:min(i+opts.BatchSize, len(labels))]
+		if err := c.reconcileBatch(ctx, vectorLabel, batch, report); err != nil {
+			// Labels not yet checked are picked up by the next run
+			c.dsu.MarkChanged(labels[i:]...)
+			return report, fmt.Errorf("reconcile: %w", err)
+		}
+	}
+
+	c.dsu.MarkChanged(report.Missing...)
+	if report.Full {
+		c.reconciled = true
+	}
+
+	return report, nil
+}
+
+// reconcileBatch compares a batch of labels with their stored vectors and rewrites stale roots
+func (c *Classifier) reconcileBatch(ctx context.Context, vectorLabel ExtendedVectorClient, labels []string, report *ReconcileReport) error {
+	records, err := vectorLabel.Fetch(ctx, labels)
+	if err != nil {
+		return fmt.Errorf("failed to fetch label vectors: %w", err)
+	}
+
+	for _, label := range labels {
+		root, ok := c.dsu.Root(label)
+		if !ok {
+			continue
+		}
+		report.Checked++
+
+		record, ok := records[label]
+		if !ok {
+			report.Missing = append(report.Missing, label)
+			continue
+		}
+
+		oldRoot, _ := record.Metadata["root"].(string)
+		if oldRoot == root {
+			continue
+		}
+		if err := vectorLabel.UpdateMetadata(ctx, label, map[string]any{"root": root}); err != nil {
+			return fmt.Errorf("failed to update label vector %s: %w", label, err)
+		}
+		report.Updated = append(report.Updated, RootChange{Label: label, OldRoot: oldRoot, NewRoot: root})
+	}
+
+	return nil
+}
+
+// runScheduledReconcile reconciles every interval until ctx is cancelled. Each run is bounded by
+// the interval, so a hung vector index can't stall the schedule.
+func (c *Classifier) runScheduledReconcile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.scheduledReconcile(ctx, interval)
+	}
+}
+
+// scheduledReconcile runs one scheduled Reconcile, giving up after timeout
+func (c *Classifier) scheduledReconcile(ctx context.Context, timeout time.Duration) {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	report, err := c.Reconcile(runCtx, ReconcileOptions{})
+	if err != nil {
+		// A run interrupted by Close isn't a failure
+		if ctx.Err() != nil {
+			return
+		}
+		c.log().Error("scheduled reconcile failed", slog.Any("error", err))
+		c.observe().OnBackgroundError(runCtx, "", err)
+		return
+	}
+	c.log().Info("scheduled reconcile completed",
+		slog.Bool("full", report.Full),
+		slog.Int("checked", report.Checked),
+		slog.Int("updated", len(report.Updated)),
+		slog.Int("missing", len(report.Missing)),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
@@ -0,0 +1,207 @@
+package classifier_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+)
+
+// newReconcileClassifier creates a classifier whose label vectors and DSU are set up by the test
+func newReconcileClassifier(t *testing.T, dsu *disjoint_set.DSU, vectorLabel classifier.VectorClient) *classifier.Classifier {
+	t.Helper()
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   vectorLabel,
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence: &testutil.MockDSUPersistence{
+			LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	t.Cleanup(func() { clf.Close() })
+	return clf
+}
+
+func TestClassifier_Reconcile(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	dsu.Union(dsu.FindOrCreate("greeting"), dsu.FindOrCreate("hello"))
+	dsu.FindOrCreate("refund")
+	dsu.FindOrCreate("orphan")
+
+	// Both labels were cached before they were merged, and orphan was never cached
+	vectorLabel := testutil.NewMockVectorClient()
+	ctx := context.Background()
+	vectorLabel.Upsert(ctx, "greeting", []float32{0.1}, map[string]any{"label": "greeting", "root": "greeting"})
+	vectorLabel.Upsert(ctx, "hello", []float32{0.2}, map[string]any{"label": "hello", "root": "hello"})
+	vectorLabel.Upsert(ctx, "refund", []float32{0.3}, map[string]any{"label": "refund", "root": "refund"})
+
+	clf := newReconcileClassifier(t, dsu, vectorLabel)
+
+	report, err := clf.Reconcile(ctx, classifier.ReconcileOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !report.Full || report.Checked != 4 {
+		t.Errorf("Expected the first run to check all 4 labels, got %+v", report)
+	}
+	expected := []classifier.RootChange{{Label: "hello", OldRoot: "hello", NewRoot: "greeting"}}
+	if !reflect.DeepEqual(report.Updated, expected) {
+		t.Errorf("Expected updates %v, got %v", expected, report.Updated)
+	}
+	if !reflect.DeepEqual(report.Missing, []string{"orphan"}) {
+		t.Errorf("Expected orphan to be missing, got %v", report.Missing)
+	}
+	if root := vectorLabel.Storage["hello"].Metadata["root"]; root != "greeting" {
+		t.Errorf("Expected hello's root to be rewritten, got %v", root)
+	}
+
+	// The next run is incremental: only the merged cluster and the missing label are checked
+	dsu.Union(dsu.FindOrCreate("greeting"), dsu.FindOrCreate("refund"))
+	report, err = clf.Reconcile(ctx, classifier.ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.Full || report.Checked != 4 {
+		t.Errorf("Expected an incremental run over the merged cluster and orphan, got %+v", report)
+	}
+	if len(report.Updated) != 1 || report.Updated[0].Label != "refund" {
+		t.Errorf("Expected refund's root to be rewritten, got %v", report.Updated)
+	}
+
+	// Nothing merged since: only the still-missing label is checked
+	report, err = clf.Reconcile(ctx, classifier.ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.Checked != 1 || len(report.Updated) != 0 {
+		t.Errorf("Expected only orphan to be checked, got %+v", report)
+	}
+}
+
+func TestClassifier_ReconcileUnsupportedVectorClient(t *testing.T) {
+	searchOnly := struct{ classifier.VectorClient }{testutil.NewMockVectorClient()}
+	clf := newReconcileClassifier(t, disjoint_set.NewDSU(), searchOnly)
+
+	if _, err := clf.Reconcile(context.Background(), classifier.ReconcileOptions{}); !errors.Is(err, classifier.ErrUnsupportedVectorClient) {
+		t.Errorf("Expected ErrUnsupportedVectorClient, got %v", err)
+	}
+}
+
+func TestClassifier_ScheduledReconcile(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	dsu.Union(dsu.FindOrCreate("greeting"), dsu.FindOrCreate("hello"))
+
+	vectorLabel := testutil.NewMockVectorClient()
+	vectorLabel.Upsert(context.Background(), "hello", []float32{0.2}, map[string]any{"label": "hello", "root": "hello"})
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   vectorLabel,
+		LLMClient:           &testutil.MockLLMClient{},
+		DSUPersistence: &testutil.MockDSUPersistence{
+			LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+		},
+		ReconcileInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		records, _ := vectorLabel.Fetch(context.Background(), []string{"hello"})
+		if records["hello"].Metadata["root"] == "greeting" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the scheduled reconcile to rewrite hello's root")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := clf.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+// hangingLabelClient blocks label vector updates until the context is done, and reports why it was
+type hangingLabelClient struct {
+	*testutil.MockVectorClient
+	started chan struct{}
+	ended   chan error
+}
+
+func (c *hangingLabelClient) UpdateMetadata(ctx context.Context, id string, metadata map[string]any) error {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	c.ended <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestClassifier_ScheduledReconcileIsBounded(t *testing.T) {
+	newHangingClassifier := func(t *testing.T, interval time.Duration, observer classifier.Observer) (*classifier.Classifier, *hangingLabelClient) {
+		dsu := disjoint_set.NewDSU()
+		dsu.Union(dsu.FindOrCreate("greeting"), dsu.FindOrCreate("hello"))
+
+		vectorLabel := &hangingLabelClient{MockVectorClient: testutil.NewMockVectorClient(), started: make(chan struct{}, 10), ended: make(chan error, 10)}
+		vectorLabel.Upsert(context.Background(), "hello", []float32{0.2}, map[string]any{"label": "hello", "root": "hello"})
+
+		clf, err := classifier.NewClassifier(classifier.Config{
+			EmbeddingClient:     &testutil.MockEmbeddingClient{},
+			VectorClientContent: testutil.NewMockVectorClient(),
+			VectorClientLabel:   vectorLabel,
+			LLMClient:           &testutil.MockLLMClient{},
+			DSUPersistence: &testutil.MockDSUPersistence{
+				LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+			},
+			ReconcileInterval: interval,
+			Observer:          observer,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create classifier: %v", err)
+		}
+		return clf, vectorLabel
+	}
+
+	t.Run("runs time out after the interval", func(t *testing.T) {
+		observer := &testutil.MockObserver{}
+		clf, vectorLabel := newHangingClassifier(t, 10*time.Millisecond, observer)
+		defer clf.Close()
+
+		if err := <-vectorLabel.ended; !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the run to time out, got %v", err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for !slices.Contains(observer.Recorded(), "background_error ") {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the timed out run to be reported, got %v", observer.Recorded())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("close cancels a run in progress", func(t *testing.T) {
+		clf, vectorLabel := newHangingClassifier(t, 100*time.Millisecond, nil)
+
+		// Close as soon as the first run hangs, well before it would time out
+		<-vectorLabel.started
+		if err := clf.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if err := <-vectorLabel.ended; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected Close to cancel the run, got %v", err)
+		}
+	})
+}
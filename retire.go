@@ -0,0 +1,73 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// RetireLabels removes labels from their clusters, e.g. after a taxonomy cleanup, deletes their
+// stored label vectors and compacts the DSU. Clusters rooted at a retired label are re-rooted at
+// their oldest remaining member, and the root metadata of its label vectors is rewritten at once.
+// Unknown labels are ignored. Returns the number of labels retired; call SaveDSU to persist the result.
+//
+// Cached texts keep their label, so a cache hit on one adds it back as a new cluster. Retire a
+// label once its texts have been re-labelled or expired from the content cache.
+// Requires the label VectorClient to implement ExtendedVectorClient.
+func (c *Classifier) RetireLabels(ctx context.Context, labels ...string) (int, error) {
+	vectorLabel, ok := c.vectorLabel.(ExtendedVectorClient)
+	if !ok {
+		return 0, fmt.Errorf("retire labels: %w", ErrUnsupportedVectorClient)
+	}
+
+	retired := make([]string, 0, len(labels))
+	rerooted := make([]string, 0)    // Remaining members of clusters whose root was retired
+	remaining := make([][]string, 0) // Remaining members of each cluster that lost a label
+	for _, label := range labels {
+		removal, ok := c.dsu.Remove(label)
+		if !ok {
+			continue
+		}
+		retired = append(retired, label)
+		if removal.Rerooted {
+			rerooted = append(rerooted, removal.Remaining...)
+		}
+		if len(removal.Remaining) > 0 {
+			remaining = append(remaining, removal.Remaining)
+		}
+	}
+	if len(retired) == 0 {
+		return 0, nil
+	}
+
+	c.dsu.Compact()
+	c.metricsRecorder().RecordLabelSets(c.dsu.Size(), c.dsu.CountSets())
+
+	if err := vectorLabel.Delete(ctx, retired); err != nil {
+		return len(retired), fmt.Errorf("retire labels: failed to delete label vectors: %w", err)
+	}
+
+	// Keep the stored roots of re-rooted clusters accurate for Reconcile and label hints
+	for _, label := range rerooted {
+		root, ok := c.dsu.Root(label)
+		if !ok {
+			continue
+		}
+		if err := vectorLabel.UpdateMetadata(ctx, label, map[string]any{"root": root}); err != nil {
+			return len(retired), fmt.Errorf("retire labels: failed to update label vector %s: %w", label, err)
+		}
+	}
+
+	// Rename each cluster that lost a label through a member that wasn't retired later on
+	for _, members := range remaining {
+		i := slices.IndexFunc(members, c.dsu.Contains)
+		if i < 0 {
+			continue
+		}
+		if err := c.refreshCanonicalLabel(ctx, members[i]); err != nil {
+			return len(retired), fmt.Errorf("retire labels: %w", err)
+		}
+	}
+
+	return len(retired), nil
+}
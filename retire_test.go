@@ -0,0 +1,133 @@
+package classifier_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	classifier "github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestClassifier_RetireLabels(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	dsu.Union(dsu.FindOrCreate("billing"), dsu.FindOrCreate("invoice"))
+	dsu.Union(dsu.FindOrCreate("billing"), dsu.FindOrCreate("payment"))
+	dsu.FindOrCreate("obsolete")
+
+	ctx := context.Background()
+	vectorLabel := testutil.NewMockVectorClient()
+	for _, label := range dsu.Labels() {
+		root, _ := dsu.Root(label)
+		vectorLabel.Upsert(ctx, label, []float32{0.1}, map[string]any{"label": label, "root": root})
+	}
+
+	clf := newReconcileClassifier(t, dsu, vectorLabel)
+
+	retired, err := clf.RetireLabels(ctx, "billing", "obsolete", "unknown")
+	if err != nil {
+		t.Fatalf("RetireLabels failed: %v", err)
+	}
+	if retired != 2 {
+		t.Errorf("Expected 2 labels retired, got %d", retired)
+	}
+
+	if !slices.Equal(vectorLabel.DeletedIDs, []string{"billing", "obsolete"}) {
+		t.Errorf("Expected the retired label vectors to be deleted, got %v", vectorLabel.DeletedIDs)
+	}
+	for _, label := range []string{"invoice", "payment"} {
+		if root := vectorLabel.Storage[label].Metadata["root"]; root != "invoice" {
+			t.Errorf("Expected label vector %s to be re-rooted at invoice, got %v", label, root)
+		}
+	}
+
+	clusters := clf.Clusters()
+	if len(clusters) != 1 || clusters[0].Label != "invoice" || !slices.Equal(clusters[0].Members, []string{"invoice", "payment"}) {
+		t.Errorf("Expected one cluster invoice of [invoice payment], got %+v", clusters)
+	}
+}
+
+func TestClassifier_RetireLabelsTogether(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	dsu.Union(dsu.FindOrCreate("billing"), dsu.FindOrCreate("invoice"))
+	dsu.Union(dsu.FindOrCreate("billing"), dsu.FindOrCreate("payment"))
+
+	ctx := context.Background()
+	vectorLabel := testutil.NewMockVectorClient()
+	for _, label := range dsu.Labels() {
+		vectorLabel.Upsert(ctx, label, []float32{0.1}, map[string]any{"label": label, "root": "billing"})
+	}
+
+	clf := newReconcileClassifier(t, dsu, vectorLabel)
+
+	// Retiring billing promotes invoice, which is retired in turn
+	if retired, err := clf.RetireLabels(ctx, "billing", "invoice"); err != nil || retired != 2 {
+		t.Fatalf("Expected 2 labels retired, got %d, %v", retired, err)
+	}
+	if root := vectorLabel.Storage["payment"].Metadata["root"]; root != "payment" {
+		t.Errorf("Expected label vector payment to be re-rooted at itself, got %v", root)
+	}
+	if clusters := clf.Clusters(); len(clusters) != 1 || clusters[0].Label != "payment" {
+		t.Errorf("Expected one cluster payment, got %+v", clusters)
+	}
+}
+
+func TestClassifier_RetireLabelsWithStaleRoots(t *testing.T) {
+	// payment left billing's cluster, but its label vector wasn't reconciled yet
+	dsu := disjoint_set.NewDSU()
+	dsu.FindOrCreate("billing")
+	dsu.FindOrCreate("payment")
+
+	ctx := context.Background()
+	vectorLabel := testutil.NewMockVectorClient()
+	vectorLabel.Upsert(ctx, "billing", []float32{0.1}, map[string]any{"label": "billing", "root": "billing"})
+	vectorLabel.Upsert(ctx, "payment", []float32{0.1}, map[string]any{"label": "payment", "root": "billing"})
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   vectorLabel,
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) { return "pay", nil },
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{
+			LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	if _, err := clf.RetireLabels(ctx, "billing"); err != nil {
+		t.Fatalf("RetireLabels failed: %v", err)
+	}
+
+	// The new label matches payment's vector, whose stored root is the retired label
+	vectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		return []types.VectorMatch{{ID: "payment", Score: 0.95, Metadata: vectorLabel.Storage["payment"].Metadata}}, nil
+	}
+	if _, err := clf.Classify(ctx, "I want to pay my bill"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	clf.Close()
+
+	if dsu.Contains("billing") {
+		t.Error("Expected the retired label to stay retired")
+	}
+	if root, _ := dsu.Root("pay"); root != "payment" {
+		t.Errorf("Expected pay to join payment's cluster, got %q", root)
+	}
+}
+
+func TestClassifier_RetireLabelsRequiresExtendedVectorClient(t *testing.T) {
+	searchOnly := struct{ classifier.VectorClient }{testutil.NewMockVectorClient()}
+	clf := newReconcileClassifier(t, disjoint_set.NewDSU(), searchOnly)
+
+	if _, err := clf.RetireLabels(context.Background(), "billing"); !errors.Is(err, classifier.ErrUnsupportedVectorClient) {
+		t.Errorf("Expected ErrUnsupportedVectorClient, got %v", err)
+	}
+}
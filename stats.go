@@ -0,0 +1,57 @@
+package classifier
+
+import (
+	"slices"
+
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+)
+
+// LabelOrigin records how a label first entered the classifier
+type LabelOrigin = disjoint_set.Origin
+
+const (
+	// LabelOriginUnknown is the origin of labels seen only in cached texts, or saved before origins were tracked
+	LabelOriginUnknown = disjoint_set.OriginUnknown
+
+	// LabelOriginLLM marks labels generated by the LLM. Classify records it automatically.
+	LabelOriginLLM = disjoint_set.OriginLLM
+
+	// LabelOriginCorrection marks labels supplied by a human correcting a classification
+	LabelOriginCorrection = disjoint_set.OriginCorrection
+
+	// LabelOriginImport marks labels imported from an existing taxonomy or dataset
+	LabelOriginImport = disjoint_set.OriginImport
+)
+
+// LabelMerge records a label joining another label's cluster, when, and the similarity that justified it
+type LabelMerge = disjoint_set.Merge
+
+// LabelStats describes a label's usage, origin and merge history
+type LabelStats = disjoint_set.LabelStats
+
+// LabelStats returns the statistics of a label, and false if it is unknown. Its merge history
+// explains why it belongs to its cluster.
+func (c *Classifier) LabelStats(label string) (LabelStats, bool) {
+	return c.dsu.Stats(label)
+}
+
+// AllLabelStats returns the statistics of every label, sorted by label
+func (c *Classifier) AllLabelStats() []LabelStats {
+	labels := c.dsu.Labels()
+	slices.Sort(labels)
+
+	stats := make([]LabelStats, 0, len(labels))
+	for _, label := range labels {
+		// Skip labels retired since they were listed
+		if s, ok := c.dsu.Stats(label); ok {
+			stats = append(stats, s)
+		}
+	}
+	return stats
+}
+
+// SetLabelOrigin records how a label added outside Classify entered the classifier, e.g. by a
+// correction or an import. The label is added if it is unknown; a known origin is never overwritten.
+func (c *Classifier) SetLabelOrigin(label string, origin LabelOrigin) {
+	c.dsu.SetOrigin(label, origin)
+}
@@ -0,0 +1,67 @@
+package classifier_test
+
+import (
+	"context"
+	"testing"
+
+	classifier "github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/internal/disjoint_set"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+func TestClassifier_LabelStats(t *testing.T) {
+	dsu := disjoint_set.NewDSU()
+	dsu.FindOrCreate("greeting")
+
+	mockVectorLabel := testutil.NewMockVectorClient()
+	mockVectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		return []types.VectorMatch{{ID: "greeting", Score: 0.91, Metadata: map[string]any{"root": "greeting"}}}, nil
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   mockVectorLabel,
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) { return "hello", nil },
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{
+			LoadFunc: func() (*disjoint_set.DSU, error) { return dsu, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	defer clf.Close()
+
+	ctx := context.Background()
+	if _, err := clf.Classify(ctx, "hi there"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	clf.SetLabelOrigin("greeting", classifier.LabelOriginImport)
+	clf.SetLabelOrigin("hello", classifier.LabelOriginCorrection)
+
+	stats, ok := clf.LabelStats("hello")
+	if !ok {
+		t.Fatal("Expected stats for hello")
+	}
+	if stats.Count != 1 || stats.Root != "greeting" || stats.LastSeen.IsZero() {
+		t.Errorf("Expected hello observed once under greeting, got %+v", stats)
+	}
+	if stats.Origin != classifier.LabelOriginLLM {
+		t.Errorf("Expected the LLM origin to be kept, got %q", stats.Origin)
+	}
+	if len(stats.Merges) != 1 || stats.Merges[0].Into != "greeting" || stats.Merges[0].Score != 0.91 || stats.Merges[0].At.IsZero() {
+		t.Errorf("Expected one merge into greeting at 0.91, got %+v", stats.Merges)
+	}
+
+	all := clf.AllLabelStats()
+	if len(all) != 2 || all[0].Label != "greeting" || all[0].Origin != classifier.LabelOriginImport || all[0].Count != 0 {
+		t.Errorf("Expected imported greeting never observed, got %+v", all)
+	}
+
+	if _, ok := clf.LabelStats("unknown"); ok {
+		t.Error("Expected no stats for an unknown label")
+	}
+}
@@ -0,0 +1,90 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LoadTaxonomyFile loads a JSON taxonomy mapping parent categories to their labels, e.g.
+// {"billing": ["refund_request", "invoice_question"]}, and returns it as label -> parent
+func LoadTaxonomyFile(filepath string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read taxonomy from file %s: %w", filepath, err)
+	}
+
+	var categories map[string][]string
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal taxonomy from file %s: %w", filepath, err)
+	}
+
+	taxonomy := make(map[string]string)
+	for parent, labels := range categories {
+		for _, label := range labels {
+			if existing, ok := taxonomy[label]; ok && existing != parent {
+				return nil, fmt.Errorf("label %q listed under both %q and %q", label, existing, parent)
+			}
+			taxonomy[label] = parent
+		}
+	}
+
+	return taxonomy, nil
+}
+
+// Clusters returns every label cluster with its parent category, sorted by root label
+func (c *Classifier) Clusters() []Cluster {
+	sets := c.dsu.Sets()
+
+	clusters := make([]Cluster, 0, len(sets))
+	for root, members := range sets {
+		clusters = append(clusters, Cluster{
+			Label:     root,
+			Canonical: c.resultLabel(root),
+			Parent:    c.dsu.Parent(root),
+			Members:   members,
+		})
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Label < clusters[j].Label
+	})
+	return clusters
+}
+
+// Categories rolls clusters up by parent category, sorted by name. Clusters without a parent are omitted.
+func (c *Classifier) Categories() []Category {
+	byName := make(map[string]*Category)
+	for _, cluster := range c.Clusters() {
+		if cluster.Parent == "" {
+			continue
+		}
+
+		category, ok := byName[cluster.Parent]
+		if !ok {
+			category = &Category{Name: cluster.Parent}
+			byName[cluster.Parent] = category
+		}
+		category.Clusters = append(category.Clusters, cluster.Label)
+		category.LabelCount += len(cluster.Members)
+	}
+
+	categories := make([]Category, 0, len(byName))
+	for _, category := range byName {
+		categories = append(categories, *category)
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
+
+// labelPath returns the hierarchy of the label's cluster from coarse to fine, ending with name
+func (c *Classifier) labelPath(label string, name string) []string {
+	if parent := c.dsu.Parent(label); parent != "" && parent != name {
+		return []string{parent, name}
+	}
+	return []string{name}
+}
@@ -0,0 +1,205 @@
+package classifier_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+)
+
+// mockParentProposer returns a fixed parent category
+type mockParentProposer struct {
+	parent     string
+	categories []string
+	callCount  int
+}
+
+func (m *mockParentProposer) ProposeParent(ctx context.Context, label string, categories []string) (string, error) {
+	m.callCount++
+	m.categories = categories
+	return m.parent, nil
+}
+
+func newHierarchyClassifier(t *testing.T, llmLabel string, labelMatch *types.VectorMatch, cfg classifier.Config) *classifier.Classifier {
+	t.Helper()
+
+	mockVectorLabel := testutil.NewMockVectorClient()
+	mockVectorLabel.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		if labelMatch == nil {
+			return []types.VectorMatch{}, nil
+		}
+		return []types.VectorMatch{*labelMatch}, nil
+	}
+
+	cfg.EmbeddingClient = &testutil.MockEmbeddingClient{}
+	cfg.VectorClientContent = testutil.NewMockVectorClient()
+	cfg.VectorClientLabel = mockVectorLabel
+	cfg.LLMClient = &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			return llmLabel, nil
+		},
+	}
+	cfg.DSUPersistence = &testutil.MockDSUPersistence{}
+
+	clf, err := classifier.NewClassifier(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+	return clf
+}
+
+func TestClassifier_Hierarchy_Taxonomy(t *testing.T) {
+	clf := newHierarchyClassifier(t, "refund_request", nil, classifier.Config{
+		Taxonomy: map[string]string{"refund_request": "billing"},
+	})
+
+	result, err := clf.Classify(context.Background(), "I want my money back")
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	if len(result.Path) != 2 || result.Path[0] != "billing" || result.Path[1] != "refund_request" {
+		t.Errorf("Expected path [billing refund_request], got %v", result.Path)
+	}
+}
+
+func TestClassifier_Hierarchy_ParentThreshold(t *testing.T) {
+	// Nearest label is related (0.70) but not close enough to merge (0.80)
+	clf := newHierarchyClassifier(t, "refund_request", &types.VectorMatch{
+		ID:       "billing",
+		Score:    0.70,
+		Metadata: map[string]any{"root": "billing"},
+	}, classifier.Config{
+		MinSimilarityParent: 0.60,
+	})
+
+	result, err := clf.Classify(context.Background(), "I want my money back")
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	if result.Label != "refund_request" {
+		t.Errorf("Expected label not to be merged, got '%s'", result.Label)
+	}
+
+	if len(result.Path) != 2 || result.Path[0] != "billing" {
+		t.Errorf("Expected path under 'billing', got %v", result.Path)
+	}
+
+	if metrics := clf.GetMetrics(); metrics.ConvergedLabels != 2 {
+		t.Errorf("Expected 2 separate clusters, got %d", metrics.ConvergedLabels)
+	}
+}
+
+func TestClassifier_Hierarchy_Proposer(t *testing.T) {
+	proposer := &mockParentProposer{parent: "greetings"}
+	clf := newHierarchyClassifier(t, "hello", nil, classifier.Config{
+		ParentProposer: proposer,
+	})
+
+	result, err := clf.Classify(context.Background(), "hi there")
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+
+	if proposer.callCount != 1 {
+		t.Errorf("Expected proposer to be called once, got %d", proposer.callCount)
+	}
+
+	if len(result.Path) != 2 || result.Path[0] != "greetings" {
+		t.Errorf("Expected path under 'greetings', got %v", result.Path)
+	}
+
+	// Clusters with a parent are not re-proposed
+	if _, err := clf.Classify(context.Background(), "hi there"); err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if proposer.callCount != 1 {
+		t.Errorf("Expected proposer not to be called again, got %d calls", proposer.callCount)
+	}
+}
+
+func TestClassifier_Categories_RollUp(t *testing.T) {
+	labels := []string{"refund_request", "invoice_question", "hello"}
+	call := 0
+	mockLLM := &testutil.MockLLMClient{
+		ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+			label := labels[call]
+			call++
+			return label, nil
+		},
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: testutil.NewMockVectorClient(),
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient:           mockLLM,
+		DSUPersistence:      &testutil.MockDSUPersistence{},
+		Taxonomy: map[string]string{
+			"refund_request":   "billing",
+			"invoice_question": "billing",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	for range labels {
+		if _, err := clf.Classify(context.Background(), "text"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+	}
+
+	clusters := clf.Clusters()
+	if len(clusters) != 3 {
+		t.Fatalf("Expected 3 clusters, got %d", len(clusters))
+	}
+
+	categories := clf.Categories()
+	if len(categories) != 1 {
+		t.Fatalf("Expected 1 category, got %d", len(categories))
+	}
+
+	if categories[0].Name != "billing" || len(categories[0].Clusters) != 2 || categories[0].LabelCount != 2 {
+		t.Errorf("Unexpected billing roll-up: %+v", categories[0])
+	}
+}
+
+func TestLoadTaxonomyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "taxonomy.json")
+	content := `{"billing": ["refund_request", "invoice_question"], "social": ["greeting"]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	taxonomy, err := classifier.LoadTaxonomyFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load taxonomy: %v", err)
+	}
+
+	if taxonomy["invoice_question"] != "billing" || taxonomy["greeting"] != "social" {
+		t.Errorf("Unexpected taxonomy: %v", taxonomy)
+	}
+
+	t.Run("conflicting parents", func(t *testing.T) {
+		conflict := filepath.Join(t.TempDir(), "conflict.json")
+		if err := os.WriteFile(conflict, []byte(`{"a": ["x"], "b": ["x"]}`), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		if _, err := classifier.LoadTaxonomyFile(conflict); err == nil {
+			t.Error("Expected error for label with two parents")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := classifier.LoadTaxonomyFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("Expected error for missing file")
+		}
+	})
+}
@@ -2,6 +2,9 @@ package testutil
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
 	"sync"
 
 	"github.com/FrenchMajesty/consistent-classifier/types"
@@ -33,14 +36,76 @@ func (m *MockEmbeddingClient) GenerateEmbedding(ctx context.Context, text string
 	return embedding, nil
 }
 
-// MockVectorClient is a mock implementation of VectorClient for testing
+// MockInputTypeEmbeddingClient is a mock implementation of InputTypeEmbeddingClient for testing
+type MockInputTypeEmbeddingClient struct {
+	MockEmbeddingClient
+	inputMu sync.Mutex
+	Inputs  map[types.EmbeddingInputType][]string // Texts embedded, by input type
+}
+
+func (m *MockInputTypeEmbeddingClient) GenerateEmbeddingForInput(ctx context.Context, text string, inputType types.EmbeddingInputType) ([]float32, error) {
+	m.inputMu.Lock()
+	if m.Inputs == nil {
+		m.Inputs = make(map[types.EmbeddingInputType][]string)
+	}
+	m.Inputs[inputType] = append(m.Inputs[inputType], text)
+	m.inputMu.Unlock()
+
+	return m.GenerateEmbedding(ctx, text)
+}
+
+// Texts returns the texts embedded with the given input type so far
+func (m *MockInputTypeEmbeddingClient) Texts(inputType types.EmbeddingInputType) []string {
+	m.inputMu.Lock()
+	defer m.inputMu.Unlock()
+	return append([]string(nil), m.Inputs[inputType]...)
+}
+
+// MockBatchEmbeddingClient is a mock implementation of BatchEmbeddingClient for testing
+type MockBatchEmbeddingClient struct {
+	MockEmbeddingClient
+	GenerateEmbeddingsFunc func(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error)
+	batchMu                sync.Mutex
+	BatchSizes             []int
+}
+
+func (m *MockBatchEmbeddingClient) GenerateEmbeddings(ctx context.Context, texts []string, inputType types.EmbeddingInputType) ([][]float32, error) {
+	m.batchMu.Lock()
+	m.BatchSizes = append(m.BatchSizes, len(texts))
+	m.batchMu.Unlock()
+
+	if m.GenerateEmbeddingsFunc != nil {
+		return m.GenerateEmbeddingsFunc(ctx, texts, inputType)
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		// Same default as MockEmbeddingClient: an embedding based on text length
+		embeddings[i] = make([]float32, 10)
+		for j := range embeddings[i] {
+			embeddings[i][j] = float32(len(text)) / 100.0
+		}
+	}
+	return embeddings, nil
+}
+
+// Batches returns the size of each batch received so far
+func (m *MockBatchEmbeddingClient) Batches() []int {
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+	return append([]int(nil), m.BatchSizes...)
+}
+
+// MockVectorClient is a mock implementation of VectorClient and ExtendedVectorClient for testing
 type MockVectorClient struct {
-	SearchFunc func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error)
-	UpsertFunc func(ctx context.Context, id string, vector []float32, metadata map[string]any) error
+	SearchFunc           func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error)
+	UpsertFunc           func(ctx context.Context, id string, vector []float32, metadata map[string]any) error
+	SearchWithFilterFunc func(ctx context.Context, vector []float32, topK int, filter map[string]any) ([]types.VectorMatch, error)
 
 	mu          sync.Mutex
 	CallCount   int
 	UpsertCount int
+	LastFilter  map[string]any
+	DeletedIDs  []string
 	Storage     map[string]struct {
 		Vector   []float32
 		Metadata map[string]any
@@ -85,13 +150,127 @@ func (m *MockVectorClient) Upsert(ctx context.Context, id string, vector []float
 	return nil
 }
 
+// SearchWithFilter records the filter and, by default, returns the matches from Search whose
+// metadata satisfies it. Supports plain equality and the $eq, $ne and $in operators.
+func (m *MockVectorClient) SearchWithFilter(ctx context.Context, vector []float32, topK int, filter map[string]any) ([]types.VectorMatch, error) {
+	m.mu.Lock()
+	m.LastFilter = filter
+	m.mu.Unlock()
+
+	if m.SearchWithFilterFunc != nil {
+		m.mu.Lock()
+		m.CallCount++
+		m.mu.Unlock()
+		return m.SearchWithFilterFunc(ctx, vector, topK, filter)
+	}
+
+	matches, err := m.Search(ctx, vector, topK)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]types.VectorMatch, 0, len(matches))
+	for _, match := range matches {
+		if matchesFilter(match.Metadata, filter) {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered, nil
+}
+
+// Fetch returns the stored vectors with the given IDs
+func (m *MockVectorClient) Fetch(ctx context.Context, ids []string) (map[string]types.VectorRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make(map[string]types.VectorRecord, len(ids))
+	for _, id := range ids {
+		if stored, ok := m.Storage[id]; ok {
+			records[id] = types.VectorRecord{ID: id, Vector: stored.Vector, Metadata: stored.Metadata}
+		}
+	}
+	return records, nil
+}
+
+// UpdateMetadata merges metadata into a stored vector's metadata
+func (m *MockVectorClient) UpdateMetadata(ctx context.Context, id string, metadata map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.Storage[id]
+	if !ok {
+		return nil
+	}
+	merged := make(map[string]any, len(stored.Metadata)+len(metadata))
+	maps.Copy(merged, stored.Metadata)
+	maps.Copy(merged, metadata)
+	stored.Metadata = merged
+	m.Storage[id] = stored
+	return nil
+}
+
+// Delete removes stored vectors and records their IDs
+func (m *MockVectorClient) Delete(ctx context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		delete(m.Storage, id)
+	}
+	m.DeletedIDs = append(m.DeletedIDs, ids...)
+	return nil
+}
+
+// BatchUpsert upserts each record in turn
+func (m *MockVectorClient) BatchUpsert(ctx context.Context, records []types.VectorRecord) error {
+	for _, record := range records {
+		if err := m.Upsert(ctx, record.ID, record.Vector, record.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesFilter reports whether metadata satisfies a Pinecone-style metadata filter
+func matchesFilter(metadata map[string]any, filter map[string]any) bool {
+	for field, condition := range filter {
+		value, ok := metadata[field]
+		operators, isOperator := condition.(map[string]any)
+		if !isOperator {
+			operators = map[string]any{"$eq": condition}
+		}
+
+		for operator, operand := range operators {
+			switch operator {
+			case "$eq":
+				if !ok || value != operand {
+					return false
+				}
+			case "$ne":
+				if ok && value == operand {
+					return false
+				}
+			case "$in":
+				values, _ := operand.([]any)
+				if !ok || !slices.Contains(values, value) {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // MockLLMClient is a mock implementation of LLMClient for testing
 type MockLLMClient struct {
-	ClassifyFunc func(ctx context.Context, text string) (string, error)
+	ClassifyFunc           func(ctx context.Context, text string) (string, error)
+	ClassifyWithPromptFunc func(ctx context.Context, data types.PromptData) (string, error)
 
-	mu        sync.Mutex
-	CallCount int
-	LastText  string
+	mu         sync.Mutex
+	CallCount  int
+	LastText   string
+	LastPrompt *types.PromptData
 }
 
 func (m *MockLLMClient) Classify(ctx context.Context, text string) (string, error) {
@@ -100,6 +279,24 @@ func (m *MockLLMClient) Classify(ctx context.Context, text string) (string, erro
 	m.LastText = text
 	m.mu.Unlock()
 
+	return m.classify(ctx, text)
+}
+
+func (m *MockLLMClient) ClassifyWithPrompt(ctx context.Context, data types.PromptData) (string, error) {
+	m.mu.Lock()
+	m.CallCount++
+	m.LastText = data.Text
+	m.LastPrompt = &data
+	m.mu.Unlock()
+
+	if m.ClassifyWithPromptFunc != nil {
+		return m.ClassifyWithPromptFunc(ctx, data)
+	}
+
+	return m.classify(ctx, data.Text)
+}
+
+func (m *MockLLMClient) classify(ctx context.Context, text string) (string, error) {
 	if m.ClassifyFunc != nil {
 		return m.ClassifyFunc(ctx, text)
 	}
@@ -142,3 +339,48 @@ func (m *MockDSUPersistence) Save(dsu *disjoint_set.DSU) error {
 
 	return nil
 }
+
+// MockObserver is a mock implementation of Observer that records events as strings, e.g. "merged hello greeting 0.91"
+type MockObserver struct {
+	mu     sync.Mutex
+	Events []string
+}
+
+func (m *MockObserver) OnCacheHit(ctx context.Context, text string, label string, score float32) {
+	m.record("hit %s %s %.2f", text, label, score)
+}
+
+func (m *MockObserver) OnCacheMiss(ctx context.Context, text string) {
+	m.record("miss %s", text)
+}
+
+func (m *MockObserver) OnLLMLabel(ctx context.Context, text string, label string) {
+	m.record("llm %s %s", text, label)
+}
+
+func (m *MockObserver) OnLabelMerged(ctx context.Context, from string, to string, score float32) {
+	m.record("merged %s %s %.2f", from, to, score)
+}
+
+func (m *MockObserver) OnNewCluster(ctx context.Context, label string) {
+	m.record("new_cluster %s", label)
+}
+
+func (m *MockObserver) OnBackgroundError(ctx context.Context, label string, err error) {
+	m.record("background_error %s", label)
+}
+
+// Recorded returns a copy of the events recorded so far
+func (m *MockObserver) Recorded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.Events)
+}
+
+func (m *MockObserver) record(format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Events = append(m.Events, fmt.Sprintf(format, args...))
+}
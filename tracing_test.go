@@ -0,0 +1,122 @@
+package classifier_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FrenchMajesty/consistent-classifier"
+	"github.com/FrenchMajesty/consistent-classifier/testutil"
+	"github.com/FrenchMajesty/consistent-classifier/types"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttr returns the value of the attribute on the span, if present
+func spanAttr(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestClassifier_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	hit := false
+	mockVectorContent := testutil.NewMockVectorClient()
+	mockVectorContent.SearchFunc = func(ctx context.Context, vector []float32, topK int) ([]types.VectorMatch, error) {
+		if hit {
+			return []types.VectorMatch{{ID: "id", Score: 0.93, Metadata: map[string]any{"label": "greeting"}}}, nil
+		}
+		return []types.VectorMatch{}, nil
+	}
+
+	clf, err := classifier.NewClassifier(classifier.Config{
+		EmbeddingClient:     &testutil.MockEmbeddingClient{},
+		VectorClientContent: mockVectorContent,
+		VectorClientLabel:   testutil.NewMockVectorClient(),
+		LLMClient: &testutil.MockLLMClient{
+			ClassifyFunc: func(ctx context.Context, text string) (string, error) {
+				return "greeting", nil
+			},
+		},
+		DSUPersistence: &testutil.MockDSUPersistence{},
+		TracerProvider: tp,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	t.Run("cache miss", func(t *testing.T) {
+		exporter.Reset()
+		if _, err := clf.Classify(context.Background(), "hello"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+
+		spans := exporter.GetSpans()
+		counts := make(map[string]int)
+		var root tracetest.SpanStub
+		for _, span := range spans {
+			counts[span.Name]++
+			if span.Name == "classifier.Classify" {
+				root = span
+			}
+		}
+
+		expected := map[string]int{
+			"classifier.Classify":   1,
+			"classifier.embed":      1,
+			"classifier.search":     1,
+			"classifier.llm":        1,
+			"classifier.background": 1,
+			"classifier.cluster":    1,
+			"classifier.upsert":     2,
+		}
+		for name, count := range expected {
+			if counts[name] != count {
+				t.Errorf("Expected %d %q spans, got %d", count, name, counts[name])
+			}
+		}
+
+		// Every stage span belongs to the Classify trace
+		for _, span := range spans {
+			if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+				t.Errorf("Span %q is not part of the Classify trace", span.Name)
+			}
+		}
+
+		if value, ok := spanAttr(root, "classifier.cache_hit"); !ok || value.AsBool() {
+			t.Error("Expected cache_hit=false attribute on Classify span")
+		}
+		if value, ok := spanAttr(root, "classifier.label"); !ok || value.AsString() != "greeting" {
+			t.Error("Expected label attribute on Classify span")
+		}
+	})
+
+	t.Run("cache hit", func(t *testing.T) {
+		exporter.Reset()
+		hit = true
+		if _, err := clf.Classify(context.Background(), "hello again"); err != nil {
+			t.Fatalf("Classify failed: %v", err)
+		}
+
+		for _, span := range exporter.GetSpans() {
+			if span.Name == "classifier.llm" || span.Name == "classifier.background" {
+				t.Errorf("Unexpected span %q on cache hit", span.Name)
+			}
+			if span.Name != "classifier.Classify" {
+				continue
+			}
+			if value, ok := spanAttr(span, "classifier.cache_hit"); !ok || !value.AsBool() {
+				t.Error("Expected cache_hit=true attribute on Classify span")
+			}
+			if value, ok := spanAttr(span, "classifier.similarity"); !ok || value.AsFloat64() < 0.92 {
+				t.Errorf("Expected similarity attribute on Classify span, got %v", value.AsFloat64())
+			}
+		}
+	})
+}
@@ -7,12 +7,20 @@ type Result struct {
 	// Label is the classification category assigned to the text
 	Label string
 
+	// Path is the label hierarchy from coarse to fine, e.g. ["billing", "refund_request"].
+	// It contains only the label when its cluster has no parent category.
+	Path []string
+
 	// CacheHit indicates whether the classification was retrieved from the vector cache
 	CacheHit bool
 
 	// Confidence is the similarity score if cache hit, 0 otherwise
 	Confidence float32
 
+	// Degraded indicates the label is the best cache match below the similarity threshold, served
+	// because the LLM was unavailable. Only set when Config.FallbackToCache is enabled.
+	Degraded bool
+
 	// UserFacingLatency is the time the user waited for the classification
 	UserFacingLatency time.Duration
 
@@ -21,6 +29,34 @@ type Result struct {
 	BackgroundLatency time.Duration
 }
 
+// Cluster describes a set of merged labels
+type Cluster struct {
+	// Label is the root label of the cluster
+	Label string
+
+	// Canonical is the label Classify returns for the cluster. It is the root label unless a
+	// CanonicalLabelStrategy chose another.
+	Canonical string
+
+	// Parent is the cluster's parent category, empty if none
+	Parent string
+
+	// Members are all labels in the cluster, including the root
+	Members []string
+}
+
+// Category rolls up the clusters that share a parent category
+type Category struct {
+	// Name is the parent category
+	Name string
+
+	// Clusters are the root labels of the clusters in this category
+	Clusters []string
+
+	// LabelCount is the total number of labels across all clusters in this category
+	LabelCount int
+}
+
 // Metrics provides statistics about the classifier's state
 type Metrics struct {
 	// UniqueLabels is the total number of unique labels seen
@@ -31,4 +67,16 @@ type Metrics struct {
 
 	// CacheHitRate is the percentage of classifications served from cache
 	CacheHitRate float32
+
+	// Classifications is the total number of successful classifications
+	Classifications int
+
+	// NewLabels is the number of cache misses where the LLM returned a label not seen before
+	NewLabels int
+
+	// NewLabelsPer1K is the number of new labels created per 1,000 classifications
+	NewLabelsPer1K float32
+
+	// BackgroundQueueDepth is the number of background tasks (clustering, vector upserts) in flight
+	BackgroundQueueDepth int
 }
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Provider names used in ProviderError
+const (
+	ProviderOpenAI   = "openai"
+	ProviderVoyage   = "voyage"
+	ProviderPinecone = "pinecone"
+)
+
+// ProviderError wraps a failure returned by an external provider (LLM, embedding or vector store)
+type ProviderError struct {
+	Provider   string // Provider name, e.g. ProviderOpenAI
+	Op         string // Operation that failed, e.g. "chat", "embed", "search"
+	StatusCode int    // HTTP status code of the last response, or 0 if unknown
+	Retryable  bool   // Whether retrying the call later may succeed
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s %s failed with status %d: %v", e.Provider, e.Op, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s %s failed: %v", e.Provider, e.Op, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Provider failure categories. Provider errors match these with errors.Is, so callers can act on
+// the cause without knowing which provider produced it.
+var (
+	ErrRateLimited    = errors.New("rate limited by provider")
+	ErrQuotaExceeded  = errors.New("provider quota exceeded")
+	ErrContextLength  = errors.New("input exceeds the model's context length")
+	ErrInvalidRequest = errors.New("invalid request to provider")
+	ErrContentFilter  = errors.New("input rejected by provider content filter")
+	ErrProviderServer = errors.New("provider server error")
+)
+
+// ErrDimensionMismatch is returned when an embedding's dimension doesn't match the vector index
+var ErrDimensionMismatch = errors.New("embedding dimension does not match the vector index")
@@ -0,0 +1,63 @@
+package types
+
+import "time"
+
+// Classification outcomes reported to a MetricsRecorder
+const (
+	OutcomeHit   = "hit"
+	OutcomeMiss  = "miss"
+	OutcomeError = "error"
+
+	// OutcomeDegraded is a below-threshold cache match served while the LLM circuit breaker is open
+	OutcomeDegraded = "degraded"
+)
+
+// Pipeline stages reported to a MetricsRecorder
+const (
+	StageEmbed   = "embed"
+	StageSearch  = "search"
+	StageLLM     = "llm"
+	StageCluster = "cluster"
+	StageUpsert  = "upsert"
+)
+
+// MetricsRecorder receives instrumentation events from the classifier and its adapters.
+// Implementations must be safe for concurrent use.
+type MetricsRecorder interface {
+	// RecordClassification counts a Classify call by outcome (hit, miss or error)
+	RecordClassification(outcome string)
+
+	// RecordStageLatency observes the duration of a pipeline stage
+	RecordStageLatency(stage string, duration time.Duration)
+
+	// RecordQueueDepth reports the number of background tasks in flight
+	RecordQueueDepth(depth int)
+
+	// RecordLabelSets reports the number of labels and label clusters in the DSU
+	RecordLabelSets(labels int, sets int)
+
+	// RecordRetry counts a retry attempt against the named API
+	RecordRetry(api string)
+
+	// RecordHedge counts a call to the named API that sent a hedged attempt. won reports whether
+	// the hedge returned first.
+	RecordHedge(api string, won bool)
+
+	// RecordRateLimitWait observes how long a request to the named API waited for the client-side rate limiter
+	RecordRateLimitWait(api string, wait time.Duration)
+
+	// RecordTokenUsage counts LLM tokens consumed by the given model
+	RecordTokenUsage(model string, promptTokens int, completionTokens int)
+}
+
+// NopMetricsRecorder discards all events. Embed it to implement only part of MetricsRecorder.
+type NopMetricsRecorder struct{}
+
+func (NopMetricsRecorder) RecordClassification(outcome string)                               {}
+func (NopMetricsRecorder) RecordStageLatency(stage string, duration time.Duration)           {}
+func (NopMetricsRecorder) RecordQueueDepth(depth int)                                        {}
+func (NopMetricsRecorder) RecordLabelSets(labels int, sets int)                              {}
+func (NopMetricsRecorder) RecordRetry(api string)                                            {}
+func (NopMetricsRecorder) RecordHedge(api string, won bool)                                  {}
+func (NopMetricsRecorder) RecordRateLimitWait(api string, wait time.Duration)                {}
+func (NopMetricsRecorder) RecordTokenUsage(model string, promptTokens, completionTokens int) {}
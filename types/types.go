@@ -6,3 +6,40 @@ type VectorMatch struct {
 	Score    float32
 	Metadata map[string]any
 }
+
+// VectorRecord is a stored vector with its metadata
+type VectorRecord struct {
+	ID       string
+	Vector   []float32
+	Metadata map[string]any
+}
+
+// EmbeddingInputType tells the embedding model what the embedded text is used for
+type EmbeddingInputType string
+
+const (
+	// EmbeddingInputDefault leaves the input type unset
+	EmbeddingInputDefault EmbeddingInputType = ""
+	// EmbeddingInputQuery is text searched for in the vector cache
+	EmbeddingInputQuery EmbeddingInputType = "query"
+	// EmbeddingInputDocument is text stored in the vector cache
+	EmbeddingInputDocument EmbeddingInputType = "document"
+)
+
+// LabeledExample is a previously classified text retrieved from the vector cache
+type LabeledExample struct {
+	Text  string
+	Label string
+}
+
+// PromptData holds the variables available to LLM prompt templates
+type PromptData struct {
+	// Text is the input text to classify
+	Text string
+
+	// Labels are known canonical labels the LLM should prefer reusing
+	Labels []string
+
+	// Examples are nearby cached texts with their resolved labels (few-shot)
+	Examples []LabeledExample
+}